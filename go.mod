@@ -6,6 +6,7 @@ require (
 	cloud.google.com/go/firestore v1.3.0
 	github.com/fatih/structs v1.1.0
 	github.com/gorilla/mux v1.8.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	google.golang.org/api v0.30.0
 	google.golang.org/grpc v1.31.0
 )
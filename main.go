@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/cdkini/algobot/src/bot"
 	"github.com/gorilla/mux"
@@ -12,10 +17,45 @@ import (
 
 // It's alive! The application starts here.
 func main() {
+	store, err := openStore(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	bot.SetStore(store)
+
+	bot.SetZulip(requireEnv("ALGOBOT_ZULIP_URL"), requireEnv("ALGOBOT_BOT_EMAIL"))
+	bot.SetServerURL(requireEnv("ALGOBOT_SERVER_URL"))
+
+	if days := os.Getenv("ALGOBOT_PAIRING_LOOKBACK_DAYS"); days != "" {
+		lookback, err := strconv.Atoi(days)
+		if err != nil {
+			log.Fatalf("ALGOBOT_PAIRING_LOOKBACK_DAYS must be a number of days: %v", err)
+		}
+		bot.SetPairingLookback(lookback)
+	}
+
+	if name := os.Getenv("ALGOBOT_MATCHER"); name != "" {
+		if err := bot.SetMatcher(name); err != nil {
+			log.Fatalf("ALGOBOT_MATCHER: %v", err)
+		}
+	}
+
+	if ids := os.Getenv("ALGOBOT_ADMINS"); ids != "" {
+		bot.SetAdmins(strings.Split(ids, ","))
+	}
+
+	if secret := os.Getenv("ALGOBOT_LINK_SECRET"); secret != "" {
+		bot.SetLinkSecret([]byte(secret))
+	} else if err := bot.LoadLinkSecret(store, context.Background()); err != nil {
+		log.Fatalf("Links can't be signed without a secret; set ALGOBOT_LINK_SECRET or store one with the bot token: %v", err)
+	}
+
 	r := mux.NewRouter()
 	r.HandleFunc("/webhooks", bot.Webhook)
 	r.HandleFunc("/cron", bot.Cron)
 	r.HandleFunc("/config/{id}", bot.Config)
+	r.HandleFunc("/history/{id}", bot.History)
 
 	r.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
@@ -28,3 +68,60 @@ func main() {
 	log.Printf("Listening on port %s", port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), r))
 }
+
+// openStore picks the database backend from ALGOBOT_STORE:
+//   - "sqlite" keeps everything in the file at ALGOBOT_SQLITE_PATH, for self-hosting
+//   - "memory" runs everything in process, for local development
+//   - anything else uses Firestore in GOOGLE_CLOUD_PROJECT
+//
+// Either of the first two can be seeded from the JSON file at ALGOBOT_FIXTURES.
+func openStore(ctx context.Context) (bot.Store, error) {
+	switch os.Getenv("ALGOBOT_STORE") {
+	case "sqlite":
+		path := os.Getenv("ALGOBOT_SQLITE_PATH")
+		if path == "" {
+			path = "algobot.db"
+		}
+		store, err := bot.NewSQLiteStore(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Using SQLite store at %s", path)
+		return store, loadFixtures(store)
+	case "memory":
+		store := bot.NewMemoryStore()
+		log.Printf("Using in-memory store")
+		return store, loadFixtures(store)
+	default:
+		projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+		if projectID == "" {
+			return nil, errors.New("GOOGLE_CLOUD_PROJECT must name the project whose Firestore to use")
+		}
+		log.Printf("Using Firestore store of project %s", projectID)
+		return bot.NewFirestoreStore(ctx, projectID)
+	}
+}
+
+// requireEnv reads a setting AlgoBot can't run without
+func requireEnv(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		log.Fatalf("%s must be set", name)
+	}
+	return value
+}
+
+func loadFixtures(store interface{ LoadFixtures(io.Reader) error }) error {
+	path := os.Getenv("ALGOBOT_FIXTURES")
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return store.LoadFixtures(file)
+}
@@ -81,16 +81,21 @@ def clean_raw_data(data):
 
 def classify_questions(questions):
     tags.add_tags(questions)
-    psets.add_psets(questions)
+    return psets.add_psets(questions)
 
 
-def populate_firebase(questions):
+def populate_firebase(questions, pset_orders):
     client = _init_gcloud()
     for id, question in questions.items():
         doc_ref = client.collection("questions").document(str(id))
         doc_ref.set(question.jsonify())
     print(f"Successfully updated {len(questions)} documents in Firebase")
 
+    # the order each pset lists its questions in, for sequential mode
+    for name, order in pset_orders.items():
+        client.collection("problemSets").document(name).set({"questions": order})
+    print(f"Successfully updated {len(pset_orders)} problem sets in Firebase")
+
 
 def _init_gcloud():
     cred = credentials.Certificate('secrets.json')
@@ -103,8 +108,8 @@ def main():
     # try:
     raw = get_all_questions()
     clean = clean_raw_data(raw)
-    classify_questions(clean)
-    populate_firebase(clean)
+    pset_orders = classify_questions(clean)
+    populate_firebase(clean, pset_orders)
         # print("SUCCESS: Database successfully populated with LeetCode data")
     # except:
         # print("FAILURE: An exception occurred somewhere")
@@ -3,25 +3,34 @@ from bs4 import BeautifulSoup
 
 
 def add_psets(questions):
-    top_100_liked(questions)
-    top_interview(questions)
+    orders = {
+        "top100Liked": top_100_liked(questions),
+        "topInterview": top_interview(questions),
+    }
     print(f"Successfully assigned all questions under supported psets")
+    return orders
 
 
 def top_100_liked(questions):
     path = "psets/top-100-liked.txt"
+    order = []
     with open(path) as f:
         content = [c.strip() for c in f.readlines()]
         for row in content:
             if row.isnumeric() and int(row) in questions:
                 questions[int(row)].psets.append("Top 100 Liked")
+                order.append(int(row))
+    return order
 
 
 def top_interview(questions):
     path = "psets/top-interview.txt"
+    order = []
     with open(path) as f:
         content = [c.strip() for c in f.readlines()]
         for row in content:
             if row.isnumeric() and int(row) in questions:
                 questions[int(row)].psets.append("Top Interview")
+                order.append(int(row))
+    return order
 
@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// Adaptive difficulty places the recurser on a scale from 0 (easy) to 2
+// (hard) by averaging the difficulty of their recent finished questions,
+// nudged up for the ones that felt easy and down for the ones they struggled
+// with. Their mix favours the difficulties closest to that level.
+const (
+	adaptiveWindow      = 10 // how many recent finished questions count
+	adaptiveMinAttempts = 3  // fewer than this and the config's difficulties are used
+	adaptiveNudge       = 0.5
+)
+
+// difficultyMix is how likely each difficulty is to be picked, and why
+type difficultyMix struct {
+	weights map[string]float64 // add up to 1
+	// level and attempts are only set when the mix was adapted
+	level    float64
+	attempts []adaptiveAttempt
+}
+
+// adaptiveAttempt is a finished question the mix is based on
+type adaptiveAttempt struct {
+	question Question
+	session  SoloSession
+	nudge    float64
+}
+
+// evenMix spreads the odds evenly over the difficulties in the config
+func evenMix(config UserConfig) difficultyMix {
+	mix := difficultyMix{weights: make(map[string]float64)}
+	for _, difficulty := range config.SoloDifficulty {
+		mix.weights[difficulty] = 1 / float64(len(config.SoloDifficulty))
+	}
+	return mix
+}
+
+// adaptMix works out the mix from the recurser's latest finished questions.
+// It falls back on the config until there are enough of them.
+func adaptMix(config UserConfig, sessions []SoloSession, questions map[int]Question) difficultyMix {
+	var attempts []adaptiveAttempt
+	for i := len(sessions) - 1; i >= 0 && len(attempts) < adaptiveWindow; i-- {
+		question, ok := questions[sessions[i].Question]
+		rating := attemptRating(sessions[i])
+		if !ok || rating == 0 {
+			continue
+		}
+
+		attempt := adaptiveAttempt{question: question, session: sessions[i]}
+		switch {
+		case rating > passingRating:
+			attempt.nudge = adaptiveNudge
+		case rating < passingRating:
+			attempt.nudge = -adaptiveNudge
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if len(attempts) < adaptiveMinAttempts {
+		return evenMix(config)
+	}
+
+	level := 0.0
+	for _, attempt := range attempts {
+		level += float64(difficultyLevels[attempt.question.Difficulty]) + attempt.nudge
+	}
+	level /= float64(len(attempts))
+	level = math.Max(0, math.Min(float64(len(difficultyOptions)-1), level))
+
+	// each difficulty gets more weight the closer it is to their level
+	mix := difficultyMix{weights: make(map[string]float64), level: level, attempts: attempts}
+	total := 0.0
+	for _, difficulty := range difficultyOptions {
+		weight := math.Max(0, 1-math.Abs(float64(difficultyLevels[difficulty])-level))
+		if weight > 0 {
+			mix.weights[difficulty] = weight
+			total += weight
+		}
+	}
+	for difficulty := range mix.weights {
+		mix.weights[difficulty] /= total
+	}
+	return mix
+}
+
+// currentDifficultyMix is the mix the recurser's next question is picked from
+func currentDifficultyMix(recurser Recurser, store Store, ctx context.Context) difficultyMix {
+	if !recurser.Config.AdaptiveDifficulty {
+		return evenMix(recurser.Config)
+	}
+
+	sessions, err := store.SoloSessions(ctx, recurser.Id)
+	if err != nil {
+		log.Println(err)
+		return evenMix(recurser.Config)
+	}
+	if len(sessions) > 2*adaptiveWindow {
+		sessions = sessions[len(sessions)-2*adaptiveWindow:]
+	}
+
+	return adaptMix(recurser.Config, sessions, lookupQuestions(sessions, store, ctx))
+}
+
+// difficulties lists the difficulties that can come up, easiest first
+func (m difficultyMix) difficulties() []string {
+	var difficulties []string
+	for _, difficulty := range difficultyOptions {
+		if m.weights[difficulty] > 0 {
+			difficulties = append(difficulties, difficulty)
+		}
+	}
+	return difficulties
+}
+
+// pick draws a difficulty according to the mix
+func (m difficultyMix) pick(r *rand.Rand) string {
+	difficulties := m.difficulties()
+	if len(difficulties) == 0 {
+		return ""
+	}
+
+	x := r.Float64()
+	for _, difficulty := range difficulties {
+		x -= m.weights[difficulty]
+		if x < 0 {
+			return difficulty
+		}
+	}
+	return difficulties[len(difficulties)-1]
+}
+
+func fmtDifficultyMix(mix difficultyMix, config UserConfig) string {
+	var b strings.Builder
+
+	switch {
+	case !config.AdaptiveDifficulty:
+		b.WriteString("Your questions are picked evenly from the difficulties in your config. Use `set adaptive on` to have them follow how you're doing instead.\n")
+	case mix.attempts == nil:
+		b.WriteString(fmt.Sprintf("Adaptive difficulty is on, but I need at least %d finished questions to go on. Until then your questions are picked evenly from the difficulties in your config. Use `done`, `gaveup` and `rate` to tell me how they go!\n", adaptiveMinAttempts))
+	default:
+		b.WriteString(fmt.Sprintf("Adaptive difficulty is on. Going by your last %s, you're at level %.1f (0 is easy, 1 medium and 2 hard).\n", pluralize(len(mix.attempts), "finished question"), mix.level))
+	}
+
+	b.WriteString("\n**Your mix:**\n")
+	for _, difficulty := range difficultyOptions {
+		b.WriteString(fmt.Sprintf("* %s: %.0f%%\n", difficulty, 100*mix.weights[difficulty]))
+	}
+
+	if mix.attempts != nil {
+		b.WriteString("\n**Why:**\n")
+		for _, attempt := range mix.attempts {
+			b.WriteString(fmt.Sprintf("* [%s](%s) (%s): %s", attempt.question.Name, attempt.question.URL, attempt.question.Difficulty, fmtAttempt(attempt.session)))
+			switch {
+			case attempt.nudge > 0:
+				b.WriteString(", nudging you up")
+			case attempt.nudge < 0:
+				b.WriteString(", nudging you down")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// fmtAttempt describes how a finished session went, e.g. "solved, rated 4/5"
+func fmtAttempt(session SoloSession) string {
+	var parts []string
+	switch session.Outcome {
+	case outcomeSolved:
+		parts = append(parts, "solved")
+	case outcomeGaveUp:
+		parts = append(parts, "gave up")
+	}
+	if session.Rating != 0 {
+		parts = append(parts, fmt.Sprintf("rated %d/%d", session.Rating, maxRating))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,644 @@
+package bot
+
+// This file implements Edmonds' blossom algorithm for maximum weight matching
+// in general graphs, in O(n^3) time. It is a port of Joris van Rantwijk's
+// well known Python implementation (mwmatching.py), which follows Galil's
+// "Efficient Algorithms for Finding Maximum Matching in Graphs" (1986).
+// Weights are integers, so the dual variables never need fractions.
+
+// weightedEdge connects vertices i and j with the given weight
+type weightedEdge struct {
+	i, j   int
+	weight int
+}
+
+type blossomMatcher struct {
+	edges          []weightedEdge
+	nvertex        int
+	maxCardinality bool
+
+	// endpoint[p] is the vertex at endpoint p; edge k has endpoints 2k and 2k+1
+	endpoint []int
+	// neighbend[v] lists the remote endpoints of the edges attached to v
+	neighbend [][]int
+	// mate[v] is the remote endpoint of v's matched edge, or -1
+	mate []int
+	// label is 0 (free), 1 (S) or 2 (T) for top-level blossoms and vertices
+	label    []int
+	labelend []int
+	// inblossom[v] is the top-level blossom containing vertex v
+	inblossom        []int
+	blossomparent    []int
+	blossomchilds    [][]int
+	blossombase      []int
+	blossomendps     [][]int
+	bestedge         []int
+	blossombestedges [][]int
+	unusedblossoms   []int
+	dualvar          []int
+	allowedge        []bool
+	queue            []int
+}
+
+// maxWeightMatching returns mate, where mate[v] is the vertex matched to v or
+// -1. With maxCardinality set, only matchings with the most edges possible
+// are considered and the heaviest of those is returned.
+func maxWeightMatching(nvertex int, edges []weightedEdge, maxCardinality bool) []int {
+	mate := make([]int, nvertex)
+	for i := range mate {
+		mate[i] = -1
+	}
+	if len(edges) == 0 {
+		return mate
+	}
+
+	m := newBlossomMatcher(nvertex, edges, maxCardinality)
+	m.solve()
+
+	for v := 0; v < nvertex; v++ {
+		if m.mate[v] >= 0 {
+			mate[v] = m.endpoint[m.mate[v]]
+		}
+	}
+	return mate
+}
+
+func newBlossomMatcher(nvertex int, edges []weightedEdge, maxCardinality bool) *blossomMatcher {
+	nedge := len(edges)
+
+	maxweight := 0
+	for _, e := range edges {
+		if e.weight > maxweight {
+			maxweight = e.weight
+		}
+	}
+
+	m := &blossomMatcher{
+		edges:            edges,
+		nvertex:          nvertex,
+		maxCardinality:   maxCardinality,
+		endpoint:         make([]int, 2*nedge),
+		neighbend:        make([][]int, nvertex),
+		mate:             make([]int, nvertex),
+		label:            make([]int, 2*nvertex),
+		labelend:         make([]int, 2*nvertex),
+		inblossom:        make([]int, nvertex),
+		blossomparent:    make([]int, 2*nvertex),
+		blossomchilds:    make([][]int, 2*nvertex),
+		blossombase:      make([]int, 2*nvertex),
+		blossomendps:     make([][]int, 2*nvertex),
+		bestedge:         make([]int, 2*nvertex),
+		blossombestedges: make([][]int, 2*nvertex),
+		dualvar:          make([]int, 2*nvertex),
+		allowedge:        make([]bool, nedge),
+	}
+
+	for k, e := range edges {
+		m.endpoint[2*k] = e.i
+		m.endpoint[2*k+1] = e.j
+		m.neighbend[e.i] = append(m.neighbend[e.i], 2*k+1)
+		m.neighbend[e.j] = append(m.neighbend[e.j], 2*k)
+	}
+	for v := 0; v < nvertex; v++ {
+		m.mate[v] = -1
+		m.inblossom[v] = v
+		m.blossombase[v] = v
+		m.dualvar[v] = maxweight
+	}
+	for b := 0; b < 2*nvertex; b++ {
+		m.labelend[b] = -1
+		m.blossomparent[b] = -1
+		m.bestedge[b] = -1
+		if b >= nvertex {
+			m.blossombase[b] = -1
+			m.unusedblossoms = append(m.unusedblossoms, b)
+		}
+	}
+
+	return m
+}
+
+func (m *blossomMatcher) slack(k int) int {
+	e := m.edges[k]
+	return m.dualvar[e.i] + m.dualvar[e.j] - 2*e.weight
+}
+
+func (m *blossomMatcher) blossomLeaves(b int) []int {
+	if b < m.nvertex {
+		return []int{b}
+	}
+	var leaves []int
+	for _, t := range m.blossomchilds[b] {
+		leaves = append(leaves, m.blossomLeaves(t)...)
+	}
+	return leaves
+}
+
+// assignLabel labels the top-level blossom containing w with t, reached
+// through endpoint p
+func (m *blossomMatcher) assignLabel(w int, t int, p int) {
+	b := m.inblossom[w]
+	m.label[w], m.label[b] = t, t
+	m.labelend[w], m.labelend[b] = p, p
+	m.bestedge[w], m.bestedge[b] = -1, -1
+
+	if t == 1 {
+		m.queue = append(m.queue, m.blossomLeaves(b)...)
+	} else if t == 2 {
+		base := m.blossombase[b]
+		m.assignLabel(m.endpoint[m.mate[base]], 1, m.mate[base]^1)
+	}
+}
+
+// scanBlossom traces back from v and w to find either a new blossom (returning
+// its base) or an augmenting path (returning -1)
+func (m *blossomMatcher) scanBlossom(v int, w int) int {
+	var path []int
+	base := -1
+
+	for v != -1 || w != -1 {
+		b := m.inblossom[v]
+		if m.label[b]&4 != 0 {
+			base = m.blossombase[b]
+			break
+		}
+		path = append(path, b)
+		m.label[b] = 5
+
+		if m.labelend[b] == -1 {
+			v = -1
+		} else {
+			v = m.endpoint[m.labelend[b]]
+			b = m.inblossom[v]
+			v = m.endpoint[m.labelend[b]]
+		}
+		if w != -1 {
+			v, w = w, v
+		}
+	}
+
+	for _, b := range path {
+		m.label[b] = 1
+	}
+	return base
+}
+
+// addBlossom makes a new blossom out of the cycle closed by edge k
+func (m *blossomMatcher) addBlossom(base int, k int) {
+	v, w := m.edges[k].i, m.edges[k].j
+	bb := m.inblossom[base]
+	bv := m.inblossom[v]
+	bw := m.inblossom[w]
+
+	b := m.unusedblossoms[len(m.unusedblossoms)-1]
+	m.unusedblossoms = m.unusedblossoms[:len(m.unusedblossoms)-1]
+
+	m.blossombase[b] = base
+	m.blossomparent[b] = -1
+	m.blossomparent[bb] = b
+
+	var path, endps []int
+	for bv != bb {
+		m.blossomparent[bv] = b
+		path = append(path, bv)
+		endps = append(endps, m.labelend[bv])
+		v = m.endpoint[m.labelend[bv]]
+		bv = m.inblossom[v]
+	}
+	path = append(path, bb)
+	reverseInts(path)
+	reverseInts(endps)
+	endps = append(endps, 2*k)
+
+	for bw != bb {
+		m.blossomparent[bw] = b
+		path = append(path, bw)
+		endps = append(endps, m.labelend[bw]^1)
+		w = m.endpoint[m.labelend[bw]]
+		bw = m.inblossom[w]
+	}
+
+	m.blossomchilds[b] = path
+	m.blossomendps[b] = endps
+	m.label[b] = 1
+	m.labelend[b] = m.labelend[bb]
+	m.dualvar[b] = 0
+
+	for _, v := range m.blossomLeaves(b) {
+		if m.label[m.inblossom[v]] == 2 {
+			// former T-vertices become S-vertices and need scanning
+			m.queue = append(m.queue, v)
+		}
+		m.inblossom[v] = b
+	}
+
+	// keep track of the least-slack edge from the new blossom to each other S-blossom
+	bestedgeto := make([]int, 2*m.nvertex)
+	for i := range bestedgeto {
+		bestedgeto[i] = -1
+	}
+	for _, bv := range path {
+		var nblists [][]int
+		if m.blossombestedges[bv] == nil {
+			for _, v := range m.blossomLeaves(bv) {
+				var nblist []int
+				for _, p := range m.neighbend[v] {
+					nblist = append(nblist, p/2)
+				}
+				nblists = append(nblists, nblist)
+			}
+		} else {
+			nblists = [][]int{m.blossombestedges[bv]}
+		}
+
+		for _, nblist := range nblists {
+			for _, k := range nblist {
+				i, j := m.edges[k].i, m.edges[k].j
+				if m.inblossom[j] == b {
+					i, j = j, i
+				}
+				bj := m.inblossom[j]
+				if bj != b && m.label[bj] == 1 &&
+					(bestedgeto[bj] == -1 || m.slack(k) < m.slack(bestedgeto[bj])) {
+					bestedgeto[bj] = k
+				}
+			}
+		}
+		m.blossombestedges[bv] = nil
+		m.bestedge[bv] = -1
+	}
+
+	m.blossombestedges[b] = []int{}
+	for _, k := range bestedgeto {
+		if k != -1 {
+			m.blossombestedges[b] = append(m.blossombestedges[b], k)
+		}
+	}
+	m.bestedge[b] = -1
+	for _, k := range m.blossombestedges[b] {
+		if m.bestedge[b] == -1 || m.slack(k) < m.slack(m.bestedge[b]) {
+			m.bestedge[b] = k
+		}
+	}
+}
+
+// expandBlossom turns blossom b back into its sub-blossoms
+func (m *blossomMatcher) expandBlossom(b int, endstage bool) {
+	for _, s := range m.blossomchilds[b] {
+		m.blossomparent[s] = -1
+		if s < m.nvertex {
+			m.inblossom[s] = s
+		} else if endstage && m.dualvar[s] == 0 {
+			m.expandBlossom(s, endstage)
+		} else {
+			for _, v := range m.blossomLeaves(s) {
+				m.inblossom[v] = s
+			}
+		}
+	}
+
+	// a T-blossom expanded mid-stage has to relabel its sub-blossoms so the
+	// alternating tree stays intact
+	if !endstage && m.label[b] == 2 {
+		childs := m.blossomchilds[b]
+		endps := m.blossomendps[b]
+
+		entrychild := m.inblossom[m.endpoint[m.labelend[b]^1]]
+		j := indexOf(childs, entrychild)
+		var jstep, endptrick int
+		if j&1 != 0 {
+			j -= len(childs)
+			jstep = 1
+			endptrick = 0
+		} else {
+			jstep = -1
+			endptrick = 1
+		}
+
+		p := m.labelend[b]
+		for j != 0 {
+			m.label[m.endpoint[p^1]] = 0
+			m.label[m.endpoint[wrapAt(endps, j-endptrick)^endptrick^1]] = 0
+			m.assignLabel(m.endpoint[p^1], 2, p)
+			m.allowedge[wrapAt(endps, j-endptrick)/2] = true
+			j += jstep
+			p = wrapAt(endps, j-endptrick) ^ endptrick
+			m.allowedge[p/2] = true
+			j += jstep
+		}
+
+		bv := wrapAt(childs, j)
+		m.label[m.endpoint[p^1]], m.label[bv] = 2, 2
+		m.labelend[m.endpoint[p^1]], m.labelend[bv] = p, p
+		m.bestedge[bv] = -1
+		j += jstep
+
+		for wrapAt(childs, j) != entrychild {
+			bv := wrapAt(childs, j)
+			if m.label[bv] == 1 {
+				j += jstep
+				continue
+			}
+
+			labelled := -1
+			for _, v := range m.blossomLeaves(bv) {
+				if m.label[v] != 0 {
+					labelled = v
+					break
+				}
+			}
+			if labelled != -1 {
+				m.label[labelled] = 0
+				m.label[m.endpoint[m.mate[m.blossombase[bv]]]] = 0
+				m.assignLabel(labelled, 2, m.labelend[labelled])
+			}
+			j += jstep
+		}
+	}
+
+	m.label[b], m.labelend[b] = -1, -1
+	m.blossomchilds[b], m.blossomendps[b] = nil, nil
+	m.blossombase[b] = -1
+	m.blossombestedges[b] = nil
+	m.bestedge[b] = -1
+	m.unusedblossoms = append(m.unusedblossoms, b)
+}
+
+// augmentBlossom swaps matched and unmatched edges along the path through
+// blossom b from vertex v to the base
+func (m *blossomMatcher) augmentBlossom(b int, v int) {
+	t := v
+	for m.blossomparent[t] != b {
+		t = m.blossomparent[t]
+	}
+	if t >= m.nvertex {
+		m.augmentBlossom(t, v)
+	}
+
+	childs := m.blossomchilds[b]
+	endps := m.blossomendps[b]
+	i := indexOf(childs, t)
+	j := i
+	var jstep, endptrick int
+	if i&1 != 0 {
+		j -= len(childs)
+		jstep = 1
+		endptrick = 0
+	} else {
+		jstep = -1
+		endptrick = 1
+	}
+
+	for j != 0 {
+		j += jstep
+		t = wrapAt(childs, j)
+		p := wrapAt(endps, j-endptrick) ^ endptrick
+		if t >= m.nvertex {
+			m.augmentBlossom(t, m.endpoint[p])
+		}
+		j += jstep
+		t = wrapAt(childs, j)
+		if t >= m.nvertex {
+			m.augmentBlossom(t, m.endpoint[p^1])
+		}
+		m.mate[m.endpoint[p]] = p ^ 1
+		m.mate[m.endpoint[p^1]] = p
+	}
+
+	// rotate so the new base comes first
+	m.blossomchilds[b] = append(append([]int{}, childs[i:]...), childs[:i]...)
+	m.blossomendps[b] = append(append([]int{}, endps[i:]...), endps[:i]...)
+	m.blossombase[b] = m.blossombase[m.blossomchilds[b][0]]
+}
+
+// augmentMatching flips the augmenting path running through edge k
+func (m *blossomMatcher) augmentMatching(k int) {
+	v, w := m.edges[k].i, m.edges[k].j
+
+	for _, start := range [][2]int{{v, 2*k + 1}, {w, 2 * k}} {
+		s, p := start[0], start[1]
+		for {
+			bs := m.inblossom[s]
+			if bs >= m.nvertex {
+				m.augmentBlossom(bs, s)
+			}
+			m.mate[s] = p
+			if m.labelend[bs] == -1 {
+				break
+			}
+
+			t := m.endpoint[m.labelend[bs]]
+			bt := m.inblossom[t]
+			s = m.endpoint[m.labelend[bt]]
+			j := m.endpoint[m.labelend[bt]^1]
+			if bt >= m.nvertex {
+				m.augmentBlossom(bt, j)
+			}
+			m.mate[j] = m.labelend[bt]
+			p = m.labelend[bt] ^ 1
+		}
+	}
+}
+
+func (m *blossomMatcher) solve() {
+	nvertex := m.nvertex
+
+	// each stage finds one augmenting path, so there are at most n of them
+	for stage := 0; stage < nvertex; stage++ {
+		for i := range m.label {
+			m.label[i] = 0
+			m.bestedge[i] = -1
+		}
+		for b := nvertex; b < 2*nvertex; b++ {
+			m.blossombestedges[b] = nil
+		}
+		for k := range m.allowedge {
+			m.allowedge[k] = false
+		}
+		m.queue = m.queue[:0]
+
+		for v := 0; v < nvertex; v++ {
+			if m.mate[v] == -1 && m.label[m.inblossom[v]] == 0 {
+				m.assignLabel(v, 1, -1)
+			}
+		}
+
+		augmented := false
+		for {
+			for len(m.queue) > 0 && !augmented {
+				v := m.queue[len(m.queue)-1]
+				m.queue = m.queue[:len(m.queue)-1]
+
+				for _, p := range m.neighbend[v] {
+					k := p / 2
+					w := m.endpoint[p]
+					if m.inblossom[v] == m.inblossom[w] {
+						continue
+					}
+
+					kslack := 0
+					if !m.allowedge[k] {
+						kslack = m.slack(k)
+						if kslack <= 0 {
+							m.allowedge[k] = true
+						}
+					}
+
+					if m.allowedge[k] {
+						if m.label[m.inblossom[w]] == 0 {
+							m.assignLabel(w, 2, p^1)
+						} else if m.label[m.inblossom[w]] == 1 {
+							base := m.scanBlossom(v, w)
+							if base >= 0 {
+								m.addBlossom(base, k)
+							} else {
+								m.augmentMatching(k)
+								augmented = true
+								break
+							}
+						} else if m.label[w] == 0 {
+							m.label[w] = 2
+							m.labelend[w] = p ^ 1
+						}
+					} else if m.label[m.inblossom[w]] == 1 {
+						b := m.inblossom[v]
+						if m.bestedge[b] == -1 || kslack < m.slack(m.bestedge[b]) {
+							m.bestedge[b] = k
+						}
+					} else if m.label[w] == 0 {
+						if m.bestedge[w] == -1 || kslack < m.slack(m.bestedge[w]) {
+							m.bestedge[w] = k
+						}
+					}
+				}
+			}
+			if augmented {
+				break
+			}
+
+			// no augmenting path with the current duals; work out how far
+			// they can move and which kind of progress that buys us
+			deltatype := -1
+			delta, deltaedge, deltablossom := 0, -1, -1
+
+			if !m.maxCardinality {
+				deltatype = 1
+				delta = m.minVertexDual()
+			}
+			for v := 0; v < nvertex; v++ {
+				if m.label[m.inblossom[v]] == 0 && m.bestedge[v] != -1 {
+					d := m.slack(m.bestedge[v])
+					if deltatype == -1 || d < delta {
+						delta = d
+						deltatype = 2
+						deltaedge = m.bestedge[v]
+					}
+				}
+			}
+			for b := 0; b < 2*nvertex; b++ {
+				if m.blossomparent[b] == -1 && m.label[b] == 1 && m.bestedge[b] != -1 {
+					d := m.slack(m.bestedge[b]) / 2
+					if deltatype == -1 || d < delta {
+						delta = d
+						deltatype = 3
+						deltaedge = m.bestedge[b]
+					}
+				}
+			}
+			for b := nvertex; b < 2*nvertex; b++ {
+				if m.blossombase[b] >= 0 && m.blossomparent[b] == -1 && m.label[b] == 2 &&
+					(deltatype == -1 || m.dualvar[b] < delta) {
+					delta = m.dualvar[b]
+					deltatype = 4
+					deltablossom = b
+				}
+			}
+			if deltatype == -1 {
+				// only possible with maxCardinality: no further improvement
+				// is possible, so do a final delta update to make the duals optimal
+				deltatype = 1
+				delta = m.minVertexDual()
+				if delta < 0 {
+					delta = 0
+				}
+			}
+
+			for v := 0; v < nvertex; v++ {
+				switch m.label[m.inblossom[v]] {
+				case 1:
+					m.dualvar[v] -= delta
+				case 2:
+					m.dualvar[v] += delta
+				}
+			}
+			for b := nvertex; b < 2*nvertex; b++ {
+				if m.blossombase[b] >= 0 && m.blossomparent[b] == -1 {
+					switch m.label[b] {
+					case 1:
+						m.dualvar[b] += delta
+					case 2:
+						m.dualvar[b] -= delta
+					}
+				}
+			}
+
+			if deltatype == 1 {
+				break
+			} else if deltatype == 2 {
+				m.allowedge[deltaedge] = true
+				i, j := m.edges[deltaedge].i, m.edges[deltaedge].j
+				if m.label[m.inblossom[i]] == 0 {
+					i, j = j, i
+				}
+				m.queue = append(m.queue, i)
+			} else if deltatype == 3 {
+				m.allowedge[deltaedge] = true
+				m.queue = append(m.queue, m.edges[deltaedge].i)
+			} else if deltatype == 4 {
+				m.expandBlossom(deltablossom, false)
+			}
+		}
+
+		if !augmented {
+			break
+		}
+
+		// blossoms with a zero dual are no longer needed at the end of a stage
+		for b := nvertex; b < 2*nvertex; b++ {
+			if m.blossomparent[b] == -1 && m.blossombase[b] >= 0 && m.label[b] == 1 && m.dualvar[b] == 0 {
+				m.expandBlossom(b, true)
+			}
+		}
+	}
+}
+
+func (m *blossomMatcher) minVertexDual() int {
+	lowest := m.dualvar[0]
+	for v := 1; v < m.nvertex; v++ {
+		if m.dualvar[v] < lowest {
+			lowest = m.dualvar[v]
+		}
+	}
+	return lowest
+}
+
+func reverseInts(list []int) {
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+}
+
+func indexOf(list []int, value int) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// wrapAt indexes like Python does, so negative indices count from the end
+func wrapAt(list []int, i int) int {
+	n := len(list)
+	return list[((i%n)+n)%n]
+}
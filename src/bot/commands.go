@@ -0,0 +1,682 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// command is something a user can ask AlgoBot to do in a private message.
+// A command either has a handler, subcommands or both (the handler then runs
+// when no subcommand is given).
+type command struct {
+	name        string
+	aliases     []string
+	args        []argSpec
+	subcommands []*command
+	help        string
+	handler     func(req commandRequest, ctx context.Context) string
+	// admin commands are left out of help and only run for admins
+	admin bool
+}
+
+// argSpec describes one argument of a command
+type argSpec struct {
+	name string
+	// choices limits the argument to these values; matching ignores case and
+	// the value is stored as written here
+	choices []string
+	// validate, if set, checks the value once it's parsed
+	validate func(value string) error
+	optional bool
+	// variadic arguments take all remaining words and must come last
+	variadic bool
+	// validateAll, if set, checks a variadic argument's words joined back
+	// together once they're parsed
+	validateAll func(value string) error
+}
+
+// commandRequest is everything a handler needs to know about who asked for
+// what. args maps argument names to their values; single arguments have one.
+type commandRequest struct {
+	userID       string
+	userEmail    string
+	userName     string
+	recurser     Recurser
+	isSubscribed bool
+	args         map[string][]string
+}
+
+// arg returns the first value of an argument, or "" if it wasn't given
+func (r commandRequest) arg(name string) string {
+	if values := r.args[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// usageError is a known command used with the wrong arguments
+type usageError struct {
+	cmd    *command
+	path   string
+	reason string
+}
+
+func (e *usageError) Error() string {
+	if e.cmd.handler == nil {
+		return e.reason
+	}
+	return fmt.Sprintf("%s\nUsage: `%s`", e.reason, e.path+usageArgs(e.cmd))
+}
+
+// admins are the Zulip user IDs allowed to run admin commands
+var admins []string
+
+// SetAdmins changes who can run admin commands
+func SetAdmins(ids []string) {
+	admins = ids
+}
+
+// commands is the registry of everything the bot understands, in the order
+// help lists them. It's filled in by init since help refers back to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name: "subscribe",
+			help: "to start getting daily data structures and algorithms questions. Oh how fun!",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return subscribe(req.userID, req.userName, req.userEmail, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name:    "schedule",
+			aliases: []string{"pair"},
+			help:    "to add yourself to the queue for a mock interview! You'll remain in the queue until you get a match.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return schedule(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name: "cancel",
+			help: "to leave the mock interview queue.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return cancel(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name: "partner",
+			subcommands: []*command{
+				{
+					name: "again",
+					args: []argSpec{{name: "@mention", variadic: true}},
+					help: "to be paired with a past partner again. Otherwise I won't pair you with someone you met recently.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return partnerAgain(req.userID, req.recurser, req.isSubscribed, req.args["@mention"], ctx)
+					},
+				},
+			},
+		},
+		{
+			name: "skip",
+			args: []argSpec{{name: "n days|YYYY-MM-DD", validate: validateSkip, optional: true}},
+			help: "to skip tomorrow's question, the next few days' or a particular day's.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return skip(req.userID, req.recurser, req.isSubscribed, req.arg("n days|YYYY-MM-DD"), time.Now(), ctx)
+			},
+		},
+		{
+			name: "unskip",
+			help: "to undo any skips.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return unskip(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name: "pause",
+			args: []argSpec{
+				{name: "n", validate: validatePauseDays},
+				{name: "days", choices: []string{"days", "day"}},
+				{name: "pairing", choices: []string{"pairing"}, optional: true},
+			},
+			help: "to take a break from solo questions for a few days, and from pairing too if you add `pairing`.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				now := time.Now()
+				until := pauseDays(req.recurser, req.arg("n"), now)
+				return pause(req.userID, req.recurser, req.isSubscribed, until, req.arg("pairing") != "", now, ctx)
+			},
+			subcommands: []*command{
+				{
+					name: "until",
+					args: []argSpec{
+						{name: "YYYY-MM-DD", validate: validatePauseUntil},
+						{name: "pairing", choices: []string{"pairing"}, optional: true},
+					},
+					help: "to take a break until a particular day, when you'll hear from me again.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return pause(req.userID, req.recurser, req.isSubscribed, req.arg("YYYY-MM-DD"), req.arg("pairing") != "", time.Now(), ctx)
+					},
+				},
+			},
+		},
+		{
+			name: "resume",
+			help: "to come back early from a pause.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return resume(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name: "set",
+			subcommands: []*command{
+				{
+					name: "experience",
+					args: []argSpec{{name: "experience", choices: difficultyOptions}},
+					help: "to tell me how experienced you are with interview questions.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Experience = req.arg("experience")
+						}, ctx)
+					},
+				},
+				{
+					name:    "pset",
+					aliases: []string{"problemset"},
+					args:    []argSpec{{name: "problem set", choices: problemSetOptions}},
+					help:    "to choose the problem set your questions come from.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.ProblemSet = req.arg("problem set")
+						}, ctx)
+					},
+				},
+				{
+					name: "order",
+					args: []argSpec{{name: "order", choices: orderOptions}},
+					help: "to get your pset's questions at random or work through them in order.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Sequential = req.arg("order") == "sequential"
+						}, ctx)
+					},
+				},
+				{
+					name: "topics",
+					args: []argSpec{{name: "topic", choices: topicOptions, optional: true, variadic: true}},
+					help: "to choose the topics to focus on, or none to get questions on anything.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Topics = inOrder(topicOptions, req.args["topic"])
+						}, ctx)
+					},
+				},
+				{
+					name: "weak-topics",
+					args: []argSpec{{name: "weak topics", choices: switchOptions}},
+					help: "to get more questions on the topics you struggle with or haven't seen in a while.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.WeakTopics = req.arg("weak topics") == "on"
+						}, ctx)
+					},
+				},
+				{
+					name: "days",
+					args: []argSpec{{name: "day", choices: dayOptions, optional: true, variadic: true}},
+					help: "to choose the days you get a question, or none to stop solo sessions.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.SoloDays = inOrder(dayOptions, req.args["day"])
+						}, ctx)
+					},
+				},
+				{
+					name: "time",
+					args: []argSpec{{name: "time", validate: validateSoloTime}},
+					help: "to choose the hour your daily question arrives, e.g. 9am.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.SoloTime, _ = parseSoloTime(req.arg("time"))
+						}, ctx)
+					},
+				},
+				{
+					name:    "timezone",
+					aliases: []string{"tz"},
+					args:    []argSpec{{name: "time zone", validate: validateTimezone}},
+					help:    "to choose the time zone your days and times are in, e.g. America/New_York.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Timezone = req.arg("time zone")
+						}, ctx)
+					},
+				},
+				{
+					name: "difficulty",
+					args: []argSpec{{name: "difficulty", choices: difficultyOptions, variadic: true}},
+					help: "to choose the difficulties of your daily questions.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.SoloDifficulty = inOrder(difficultyOptions, req.args["difficulty"])
+						}, ctx)
+					},
+				},
+				{
+					name: "adaptive",
+					args: []argSpec{{name: "adaptive", choices: switchOptions}},
+					help: "to have the difficulty of your questions follow how you're doing.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.AdaptiveDifficulty = req.arg("adaptive") == "on"
+						}, ctx)
+					},
+				},
+				{
+					name: "pairing-difficulty",
+					args: []argSpec{{name: "difficulty", choices: difficultyOptions, variadic: true}},
+					help: "to choose the difficulties you want to be interviewed on.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.PairingDifficulty = inOrder(difficultyOptions, req.args["difficulty"])
+						}, ctx)
+					},
+				},
+				{
+					name:    "environment",
+					aliases: []string{"env"},
+					args:    []argSpec{{name: "environment", choices: environmentOptions}},
+					help:    "to choose where you'd like to hold mock interviews.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Environment = req.arg("environment")
+						}, ctx)
+					},
+				},
+				{
+					name:    "manual-question",
+					aliases: []string{"manual"},
+					args:    []argSpec{{name: "choice", choices: []string{"yes", "no"}}},
+					help:    "to pick your own questions for mock interviews instead of getting random ones.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.ManualQuestion = req.arg("choice") == "yes"
+						}, ctx)
+					},
+				},
+				{
+					name:    "comments",
+					aliases: []string{"comment"},
+					args:    []argSpec{{name: "comments", variadic: true, validateAll: validateComments}},
+					help:    "to leave a note for your interview partners.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return setConfig(req.userID, req.recurser, req.isSubscribed, func(config *UserConfig) {
+							config.Comments = strings.Join(req.args["comments"], " ")
+						}, ctx)
+					},
+				},
+			},
+		},
+		{
+			name:    "topics",
+			aliases: []string{"topic"},
+			help:    "to see the topics you're focusing on.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return topics(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+			subcommands: []*command{
+				{
+					name: "add",
+					args: []argSpec{{name: "topic", choices: topicOptions}},
+					help: "to focus on a topic.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return addTopic(req.userID, req.recurser, req.isSubscribed, req.arg("topic"), ctx)
+					},
+				},
+				{
+					name:    "remove",
+					aliases: []string{"rm"},
+					args:    []argSpec{{name: "topic", choices: topicOptions}},
+					help:    "to stop focusing on a topic.",
+					handler: func(req commandRequest, ctx context.Context) string {
+						return removeTopic(req.userID, req.recurser, req.isSubscribed, req.arg("topic"), ctx)
+					},
+				},
+			},
+		},
+		{
+			name: "progress",
+			help: "to see how far through your pset you are, topic by topic.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return progress(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name:    "done",
+			aliases: []string{"solved"},
+			args:    []argSpec{{name: "minutes", validate: validateMinutes, optional: true}},
+			help:    "to tell me you solved your last question, optionally with how many minutes it took.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return done(req.userID, req.recurser, req.isSubscribed, req.arg("minutes"), ctx)
+			},
+		},
+		{
+			name:    "gaveup",
+			aliases: []string{"giveup"},
+			args:    []argSpec{{name: "minutes", validate: validateMinutes, optional: true}},
+			help:    "to tell me you gave up on your last question. It'll come back for another try!",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return gaveUp(req.userID, req.recurser, req.isSubscribed, req.arg("minutes"), ctx)
+			},
+		},
+		{
+			name: "rate",
+			args: []argSpec{{name: "rating", choices: []string{"1", "2", "3", "4", "5"}}},
+			help: "to tell me how hard your last question felt, from 1 (couldn't solve it) to 5 (easy), so I know when to bring it back for review.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return rate(req.userID, req.recurser, req.isSubscribed, req.arg("rating"), ctx)
+			},
+		},
+		{
+			name: "stats",
+			help: "to see how your solo sessions have gone, including your streak.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return stats(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name:    "difficulty",
+			aliases: []string{"mix"},
+			help:    "to see how likely each difficulty is for your next question, and why.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return difficulty(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name:    "config",
+			aliases: []string{"settings"},
+			help:    "to review and modify your current settings.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return config(req.userID, req.recurser, req.isSubscribed)
+			},
+		},
+		{
+			name: "history",
+			help: "to see your past solo and pairing sessions, with filters for dates, topics and difficulty.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return history(req.userID, req.recurser, req.isSubscribed)
+			},
+		},
+		{
+			name: "unsubscribe",
+			help: "to part ways with AlgoBot. Note that your settings and session history will be deleted!",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return unsubscribe(req.userID, req.recurser, req.isSubscribed, ctx)
+			},
+		},
+		{
+			name:    "help",
+			aliases: []string{"commands"},
+			help:    "to see this message.",
+			handler: func(req commandRequest, ctx context.Context) string {
+				return helpText()
+			},
+		},
+		{
+			name:  "jobs",
+			help:  "to see the scheduled jobs and when they last ran.",
+			admin: true,
+			handler: func(req commandRequest, ctx context.Context) string {
+				return fmtJobs(store, ctx)
+			},
+			subcommands: []*command{
+				{
+					name:  "run",
+					args:  []argSpec{{name: "job", choices: jobNames()}},
+					help:  "to run a job right away.",
+					admin: true,
+					handler: func(req commandRequest, ctx context.Context) string {
+						zulip, err := newZulipClient(store, ctx)
+						if err != nil {
+							log.Println(err)
+							return botMessages.ReadError
+						}
+						return runJobNow(req.arg("job"), store, zulip, time.Now(), ctx)
+					},
+				},
+			},
+		},
+	}
+}
+
+// findCommand looks up a command by name or alias
+func findCommand(list []*command, word string) *command {
+	word = strings.ToLower(word)
+	for _, cmd := range list {
+		if cmd.name == word || contains(cmd.aliases, word) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// parseCmd works out which command the user asked for and checks its
+// arguments against the command's schema. Unknown commands return a nil
+// command; known commands with bad arguments return a *usageError.
+func parseCmd(cmdStr string) (*command, map[string][]string, error) {
+	cmd, path, words, err := lookupCmd(cmdStr)
+	if err != nil {
+		return cmd, nil, err
+	}
+	args, err := bindCmd(cmd, path, words)
+	return cmd, args, err
+}
+
+// lookupCmd works out which command the user asked for, returning it along
+// with its path (e.g. "set difficulty") and the words left for its arguments.
+// Unknown commands return a nil command.
+func lookupCmd(cmdStr string) (*command, string, []string, error) {
+	words := strings.Fields(cmdStr)
+	if len(words) == 0 {
+		return nil, "", nil, errors.New("the user-issued command was blank")
+	}
+
+	cmd := findCommand(commands, words[0])
+	if cmd == nil {
+		return nil, "", nil, errors.New("the user-issued command wasn't valid")
+	}
+	path := cmd.name
+	words = words[1:]
+
+	// walk down through subcommands as far as the words go
+	for len(words) > 0 {
+		sub := findCommand(cmd.subcommands, words[0])
+		if sub == nil {
+			break
+		}
+		cmd = sub
+		path += " " + sub.name
+		words = words[1:]
+	}
+	return cmd, path, words, nil
+}
+
+// bindCmd checks the words against the command's schema, returning a
+// *usageError if they don't fit
+func bindCmd(cmd *command, path string, words []string) (map[string][]string, error) {
+	if cmd.handler == nil {
+		return nil, &usageError{cmd, path, suggestSubcommands(cmd, path)}
+	}
+
+	args, err := bindArgs(cmd, words)
+	if err != nil {
+		return nil, &usageError{cmd, path, err.Error()}
+	}
+	return args, nil
+}
+
+// suggestSubcommands points someone who stopped at a command without a
+// handler to the subcommands they can use, listing them out if there are many
+func suggestSubcommands(cmd *command, path string) string {
+	if len(cmd.subcommands) <= 2 {
+		var names []string
+		for _, sub := range cmd.subcommands {
+			names = append(names, fmt.Sprintf("`%s %s`", path, sub.name))
+		}
+		return fmt.Sprintf("Did you mean %s?", strings.Join(names, " or "))
+	}
+
+	var b strings.Builder
+	b.WriteString("Did you mean one of these?\n")
+	for _, sub := range cmd.subcommands {
+		writeHelp(&b, sub, path+" ")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// bindArgs matches words up with a command's arguments
+func bindArgs(cmd *command, words []string) (map[string][]string, error) {
+	args := make(map[string][]string)
+
+	for _, spec := range cmd.args {
+		if len(words) == 0 {
+			if spec.optional {
+				break
+			}
+			return nil, fmt.Errorf("I need to know the %s.", spec.name)
+		}
+
+		values := words[:1]
+		if spec.variadic {
+			values = words
+		}
+		words = words[len(values):]
+
+		for i, value := range values {
+			if spec.choices != nil {
+				choice := findChoice(spec.choices, value)
+				if choice == "" {
+					return nil, fmt.Errorf("%q isn't a valid %s. Try one of: %s", value, spec.name, strings.Join(spec.choices, ", "))
+				}
+				values[i] = choice
+			}
+			if spec.validate != nil {
+				if err := spec.validate(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if spec.validateAll != nil {
+			if err := spec.validateAll(strings.Join(values, " ")); err != nil {
+				return nil, err
+			}
+		}
+		args[spec.name] = values
+	}
+
+	if len(words) > 0 {
+		return nil, fmt.Errorf("I didn't expect %q.", strings.Join(words, " "))
+	}
+	return args, nil
+}
+
+func findChoice(choices []string, value string) string {
+	for _, choice := range choices {
+		if strings.EqualFold(choice, value) {
+			return choice
+		}
+	}
+	return ""
+}
+
+// handleCommand answers a private message to the bot
+func handleCommand(ctx context.Context, cmdStr string, userID string, userEmail string, userName string) (string, error) {
+	cmd, path, words, err := lookupCmd(cmdStr)
+	if cmd == nil {
+		return helpText(), err
+	}
+	return dispatch(ctx, cmd, path, words, userID, userEmail, userName)
+}
+
+// dispatch checks the command's arguments, looks up the user and runs the
+// command's handler
+func dispatch(ctx context.Context, cmd *command, path string, words []string, userID string, userEmail string, userName string) (string, error) {
+	// admin commands are turned away before their arguments are checked, so
+	// their usage isn't given away either
+	if cmd.admin && !contains(admins, userID) {
+		return "Sorry, only AlgoBot's admins can do that!", nil
+	}
+
+	args, err := bindCmd(cmd, path, words)
+	if err != nil {
+		return err.Error(), err
+	}
+
+	// get the user's database entry; if there is one, that means they were already subscribed to AlgoBot
+	recurser, isSubscribed, err := store.GetRecurser(ctx, userID)
+	if err != nil {
+		return botMessages.ReadError, err
+	}
+
+	req := commandRequest{
+		userID:       userID,
+		userEmail:    userEmail,
+		userName:     userName,
+		recurser:     recurser,
+		isSubscribed: isSubscribed,
+		args:         args,
+	}
+	return cmd.handler(req, ctx), nil
+}
+
+// helpText lists every command in the registry
+func helpText() string {
+	var b strings.Builder
+	b.WriteString(botMessages.HelpIntro)
+	b.WriteString("\n\n**How to use AlgoBot:**\n")
+	for _, cmd := range commands {
+		if !cmd.admin {
+			writeHelp(&b, cmd, "")
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(botMessages.HelpFooter)
+	return b.String()
+}
+
+func writeHelp(b *strings.Builder, cmd *command, prefix string) {
+	path := prefix + cmd.name
+	if cmd.handler != nil {
+		b.WriteString(fmt.Sprintf("* `%s` %s", path+usageArgs(cmd), cmd.help))
+		if len(cmd.aliases) > 0 {
+			b.WriteString(fmt.Sprintf(" (or `%s`)", strings.Join(cmd.aliases, "`, `")))
+		}
+		b.WriteString("\n")
+	}
+	for _, sub := range cmd.subcommands {
+		writeHelp(b, sub, path+" ")
+	}
+}
+
+// usageArgs describes a command's arguments, e.g. " <topic>" or " [<n days>]"
+func usageArgs(cmd *command) string {
+	var usage strings.Builder
+	for _, spec := range cmd.args {
+		name := spec.name
+		if spec.choices != nil && len(spec.choices) <= 5 {
+			name = strings.Join(spec.choices, "|")
+		}
+		arg := "<" + name + ">"
+		if spec.variadic {
+			arg += "..."
+		}
+		if spec.optional {
+			arg = "[" + arg + "]"
+		}
+		usage.WriteString(" " + arg)
+	}
+	return usage.String()
+}
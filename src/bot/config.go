@@ -1,15 +1,16 @@
 package bot
 
 import (
-	"context"
+	"bytes"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/firestore"
-
-	"github.com/fatih/structs"
 	"github.com/gorilla/mux"
 )
 
@@ -19,6 +20,11 @@ type Recurser struct {
 	Email              string     `structs:"email" firestore:"email"`
 	IsSkippingTomorrow bool       `structs:"isSkippingTomorrow" firestore:"isSkippingTomorrow"`
 	IsPairingTomorrow  bool       `structs:"isPairingTomorrow" firestore:"isPairingTomorrow"`
+	QueuedAt           time.Time  `structs:"queuedAt,omitnested" firestore:"queuedAt"` // when they last joined the pairing queue
+	UnmatchedDays      int        `structs:"unmatchedDays" firestore:"unmatchedDays"`  // days in a row they were left without a match
+	RematchWith        []string   `structs:"rematchWith" firestore:"rematchWith"`      // past partners they're happy to pair with again
+	SkipDates          []string   `structs:"skipDates" firestore:"skipDates"`          // future days (YYYY-MM-DD) without a solo question
+	Pauses             []Pause    `structs:"pauses" firestore:"pauses"`                // date ranges they're away for
 	Config             UserConfig `structs:"config" firestore:"config"`
 }
 
@@ -29,20 +35,34 @@ func newRecurser(id string, name string, email string) Recurser {
 		Email:              email,
 		IsSkippingTomorrow: false,
 		IsPairingTomorrow:  false,
+		RematchWith:        []string{},
+		SkipDates:          []string{},
+		Pauses:             []Pause{},
 		Config:             defaultUserConfig(),
 	}
 }
 
+// stringifyUserConfig describes the recurser's settings, one line per setting
+// so that changes can be shown line by line
 func (r Recurser) stringifyUserConfig() string {
 	var b strings.Builder
 
 	b.WriteString(fmt.Sprintf("You are %s!\n\n", r.Name))
 	b.WriteString(fmt.Sprintf("Your experience level is %s.\n", r.Config.Experience))
-	b.WriteString(fmt.Sprintf("You are working through the %s pset.\n", r.Config.ProblemSet))
+	if r.Config.Sequential && r.Config.ProblemSet != "random" {
+		b.WriteString(fmt.Sprintf("You are working through the %s pset in order.\n", r.Config.ProblemSet))
+	} else {
+		b.WriteString(fmt.Sprintf("You are working through the %s pset.\n", r.Config.ProblemSet))
+	}
 
-	if len(r.Config.Topics) == 0 {
+	switch {
+	case r.Config.WeakTopics && len(r.Config.Topics) == 0:
+		b.WriteString("You are focusing on your weakest topics.\n")
+	case r.Config.WeakTopics:
+		b.WriteString(fmt.Sprintf("You are focusing on your weakest of these topics: %s\n", r.Config.Topics))
+	case len(r.Config.Topics) == 0:
 		b.WriteString("You have not selected specific topics to work on.\n")
-	} else {
+	default:
 		b.WriteString(fmt.Sprintf("You are focusing on these topics: %s\n", r.Config.Topics))
 	}
 
@@ -50,24 +70,36 @@ func (r Recurser) stringifyUserConfig() string {
 		b.WriteString("You are not scheduled for solo sessions.\n")
 	} else {
 		b.WriteString(fmt.Sprintf("You have solo sessions scheduled for these days: %s\n", r.Config.SoloDays))
+	}
+	b.WriteString(fmt.Sprintf("Your solo questions go out at %s, %s time.\n", r.Config.soloTime(), r.Config.timezone()))
+	if r.Config.AdaptiveDifficulty {
+		b.WriteString(fmt.Sprintf("Your question difficulty adapts to how you're doing, starting from: %s\n", r.Config.SoloDifficulty))
+	} else {
 		b.WriteString(fmt.Sprintf("You will receive questions of this difficulty: %s\n", r.Config.SoloDifficulty))
-		if r.IsSkippingTomorrow {
-			b.WriteString("You are set to skip tomorrow's solo session.\n")
-		}
+	}
+	if r.IsSkippingTomorrow {
+		b.WriteString("You are set to skip tomorrow's solo session.\n")
+	}
+	if resume := r.resumeDate(time.Now(), false); resume != "" {
+		b.WriteString(fmt.Sprintf("You are paused, so your solo sessions resume on %s.\n", fmtDate(resume)))
 	}
 
 	if !r.IsPairingTomorrow {
 		b.WriteString("You are not in the queue for a pairing session.\n")
 	} else {
 		b.WriteString("You are in the queue for a pairing session.\n")
-		b.WriteString(fmt.Sprintf("You will receive questions of this difficulty: %s\n", r.Config.PairingDifficulty))
-		b.WriteString(fmt.Sprintf("Your preferred environment is %s.\n", r.Config.Environment))
-		if r.Config.ManualQuestion {
-			b.WriteString("You will be choosing your own questions for your pairing sessions.\n")
-		} else {
-			b.WriteString("You will receive random questions for your pairing sessions.\n")
-		}
 	}
+	if resume := r.resumeDate(time.Now(), true); resume != "" {
+		b.WriteString(fmt.Sprintf("You are paused from pairing until %s.\n", fmtDate(resume)))
+	}
+	b.WriteString(fmt.Sprintf("You will be interviewed on questions of this difficulty: %s\n", r.Config.PairingDifficulty))
+	b.WriteString(fmt.Sprintf("Your preferred environment is %s.\n", r.Config.Environment))
+	if r.Config.ManualQuestion {
+		b.WriteString("You will be choosing your own questions for your pairing sessions.\n")
+	} else {
+		b.WriteString("You will receive random questions for your pairing sessions.\n")
+	}
+	b.WriteString(fmt.Sprintf("Your comments for your partners: %s\n", r.Config.Comments))
 
 	b.WriteString("\n")
 	return b.String()
@@ -92,74 +124,327 @@ func (r Recurser) hasPairingConfig() bool {
 }
 
 type UserConfig struct {
-	Comments          string   `structs:"comments" firestore:"comments"`
-	Environment       string   `structs:"environment" firestore:"environment"`
-	Experience        string   `structs:"experience" firestore:"experience"`
-	ProblemSet        string   `structs:"problemSet" firestore:"problemSet"`
-	Topics            []string `structs:"topics" firestore:"topics"`
-	SoloDays          []string `structs:"soloDays" firestore:"soloDays"`
-	SoloDifficulty    []string `structs:"soloDifficulty" firestore:"soloDifficulty"`
-	PairingDifficulty []string `structs:"pairingDifficulty" firestore:"pairingDifficulty"`
-	ManualQuestion    bool     `structs:"manualQuestion" firestore:"manualQuestion"`
+	Comments           string   `structs:"comments" firestore:"comments"`
+	Environment        string   `structs:"environment" firestore:"environment"`
+	Experience         string   `structs:"experience" firestore:"experience"`
+	ProblemSet         string   `structs:"problemSet" firestore:"problemSet"`
+	Sequential         bool     `structs:"sequential" firestore:"sequential"` // walk the problem set in order
+	Topics             []string `structs:"topics" firestore:"topics"`
+	WeakTopics         bool     `structs:"weakTopics" firestore:"weakTopics"` // favour the topics they do worst on
+	SoloDays           []string `structs:"soloDays" firestore:"soloDays"`
+	SoloTime           string   `structs:"soloTime" firestore:"soloTime"` // local time of day for solo questions, e.g. "11:00"
+	Timezone           string   `structs:"timezone" firestore:"timezone"` // IANA name, e.g. "America/New_York"
+	SoloDifficulty     []string `structs:"soloDifficulty" firestore:"soloDifficulty"`
+	AdaptiveDifficulty bool     `structs:"adaptiveDifficulty" firestore:"adaptiveDifficulty"` // follow recent outcomes instead of SoloDifficulty
+	PairingDifficulty  []string `structs:"pairingDifficulty" firestore:"pairingDifficulty"`
+	ManualQuestion     bool     `structs:"manualQuestion" firestore:"manualQuestion"`
+}
+
+// Solo questions go out at 11am UTC unless the recurser says otherwise. Empty
+// SoloTime and Timezone fields mean these too.
+const (
+	defaultSoloTime = "11:00"
+	defaultTimezone = "UTC"
+)
+
+func (c UserConfig) soloTime() string {
+	if c.SoloTime == "" {
+		return defaultSoloTime
+	}
+	return c.SoloTime
+}
+
+func (c UserConfig) timezone() string {
+	if c.Timezone == "" {
+		return defaultTimezone
+	}
+	return c.Timezone
+}
+
+// location is the recurser's time zone, or UTC if it can't be loaded
+func (c UserConfig) location() *time.Location {
+	loc, err := time.LoadLocation(c.timezone())
+	if err != nil {
+		log.Println(err)
+		return time.UTC
+	}
+	return loc
 }
 
+// soloHour is the hour of the day, in their time zone, that the recurser
+// gets their solo question
+func (c UserConfig) soloHour() int {
+	t, err := time.Parse(timeLayout, c.soloTime())
+	if err != nil {
+		log.Println(err)
+		t, _ = time.Parse(timeLayout, defaultSoloTime)
+	}
+	return t.Hour()
+}
+
+// The values each UserConfig field can take, matching the options on the config page
+var (
+	difficultyOptions  = []string{"easy", "medium", "hard"}
+	problemSetOptions  = []string{"top100Liked", "topInterview", "random"}
+	orderOptions       = []string{"random", "sequential"}
+	switchOptions      = []string{"on", "off"}
+	dayOptions         = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	environmentOptions = []string{"leetcode", "replit", "googleDocs"}
+	// the radio buttons for how topics and pairing questions are picked
+	topicSelectionOptions    = []string{"randomTopic", "manualTopic", "weakTopic"}
+	questionSelectionOptions = []string{"randomQuestion", "manualQuestion"}
+	topicOptions             = []string{
+		"array", "backtracking", "binarySearch", "bitManipulation", "breadth-firstSearch",
+		"depth-firstSearch", "design", "divideAndConquer", "dynamicProgramming", "graph",
+		"greedy", "hashTable", "heap", "linkedList", "math", "recursion", "slidingWindow",
+		"sort", "stack", "string", "tree", "trie", "twoPointers", "unionFind",
+	}
+)
+
 func defaultUserConfig() UserConfig {
 	return UserConfig{
-		Comments:          "N/A",
-		Environment:       "leetcode",
-		Experience:        "medium",
-		ProblemSet:        "topInterview",
-		Topics:            []string{},
-		SoloDays:          []string{"mon", "tue", "wed", "thu", "fri"},
-		SoloDifficulty:    []string{"easy", "medium"},
-		PairingDifficulty: []string{"easy", "medium"},
-		ManualQuestion:    false,
+		Comments:           "N/A",
+		Environment:        "leetcode",
+		Experience:         "medium",
+		ProblemSet:         "topInterview",
+		Sequential:         false,
+		Topics:             []string{},
+		WeakTopics:         false,
+		SoloDays:           []string{"mon", "tue", "wed", "thu", "fri"},
+		SoloTime:           defaultSoloTime,
+		Timezone:           defaultTimezone,
+		SoloDifficulty:     []string{"easy", "medium"},
+		AdaptiveDifficulty: false,
+		PairingDifficulty:  []string{"easy", "medium"},
+		ManualQuestion:     false,
+	}
+}
+
+// templateDir is where the pages the bot serves are kept
+var templateDir = "static/templates"
+
+var templateFuncs = template.FuncMap{"has": contains}
+
+// renderTemplate writes out one of the pages in templateDir
+func renderTemplate(w http.ResponseWriter, status int, name string, data interface{}) {
+	page, err := template.New(name).Funcs(templateFuncs).ParseFiles(filepath.Join(templateDir, name))
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Sorry, something went wrong on our end.", http.StatusInternalServerError)
+		return
+	}
+
+	// render it all first so a failure doesn't leave half a page
+	var b bytes.Buffer
+	if err = page.Execute(&b, data); err != nil {
+		log.Println(err)
+		http.Error(w, "Sorry, something went wrong on our end.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	b.WriteTo(w)
+}
+
+// configPage is what config.html is filled in with. The two selections are
+// radio buttons that aren't stored as such.
+type configPage struct {
+	Config            UserConfig
+	SoloTime          string
+	TopicSelection    string
+	QuestionSelection string
+	// Errors maps form fields to what's wrong with them
+	Errors map[string]string
+}
+
+func newConfigPage(config UserConfig) configPage {
+	page := configPage{
+		Config:            config,
+		SoloTime:          config.soloTime(),
+		TopicSelection:    "randomTopic",
+		QuestionSelection: "randomQuestion",
+	}
+	if config.WeakTopics {
+		page.TopicSelection = "weakTopic"
+	} else if len(config.Topics) > 0 {
+		page.TopicSelection = "manualTopic"
+	}
+	if config.ManualQuestion {
+		page.QuestionSelection = "manualQuestion"
 	}
+	return page
 }
 
+// savedPage is what saved.html is filled in with
+type savedPage struct {
+	Token   string
+	Summary []string
+}
+
+// Config serves the page behind the link the config command hands out, which
+// must carry a valid token for the ID in the path
 func Config(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if r.Method != "GET" && r.Method != "POST" {
+		fmt.Fprintf(w, "Sorry, only GET and POST methods are supported.")
+		return
+	}
+	if !authorizeLink(w, r, "config", id) {
+		return
+	}
+
+	recurser, isSubscribed, err := store.GetRecurser(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Sorry, your settings couldn't be loaded. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	if !isSubscribed {
+		http.Error(w, "You aren't subscribed to AlgoBot, so there's nothing to configure. Send subscribe to AlgoBot on Zulip first.", http.StatusNotFound)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
-		http.ServeFile(w, r, "static/templates/config.html")
+		renderTemplate(w, http.StatusOK, "config.html", newConfigPage(recurser.Config))
 	case "POST":
-		vars := mux.Vars(r)
-		handlePOST(w, r, vars["id"])
-	default:
-		fmt.Fprintf(w, "Sorry, only GET and POST methods are supported.")
+		handlePOST(w, r, recurser)
 	}
 }
 
-func handlePOST(w http.ResponseWriter, r *http.Request, id string) {
+func handlePOST(w http.ResponseWriter, r *http.Request, recurser Recurser) {
 	// Call ParseForm() to parse the raw query and update r.PostForm and r.Form.
 	if err := r.ParseForm(); err != nil {
-		fmt.Fprintf(w, "ParseForm() err: %v", err)
+		http.Error(w, fmt.Sprintf("Sorry, your settings couldn't be read: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	page := parseConfigForm(r.PostForm)
+	if len(page.Errors) > 0 {
+		renderTemplate(w, http.StatusBadRequest, "config.html", page)
 		return
 	}
 
-	// var recurser Recurser
-	ctx := context.Background()
-	// ctx := r.Context()
-	var err error
-	client, err = firestore.NewClient(ctx, "algobot-308118")
-	defer client.Close()
+	err := store.UpdateConfig(r.Context(), recurser.Id, page.Config)
 	if err != nil {
-		log.Panic(err)
+		log.Println(err)
+		http.Error(w, "Sorry, your settings couldn't be saved. Please try again.", http.StatusInternalServerError)
+		return
 	}
+	log.Println(fmt.Sprintf("%s updated their config", recurser.Name))
 
-	// Store results of POST in struct
-	config := UserConfig{
-		r.PostFormValue("comments"),
-		r.PostFormValue("environment"),
-		r.PostFormValue("experience"),
-		r.PostFormValue("questionList"),
-		r.PostForm["topics"],
-		r.PostForm["soloDays"],
-		r.PostForm["soloDifficulty"],
-		r.PostForm["pairingDifficulty"],
-		r.PostFormValue("manualQuestion") == "manualQuestion",
+	recurser.Config = page.Config
+	var summary []string
+	for _, line := range strings.Split(recurser.stringifyUserConfig(), "\n") {
+		if line != "" {
+			summary = append(summary, line)
+		}
 	}
+	renderTemplate(w, http.StatusOK, "saved.html", savedPage{Token: r.FormValue("token"), Summary: summary})
+}
 
-	// Retrieve current config / user profile and update
-	doc := client.Collection("recursers").Doc(id)
-	doc.Update(ctx, []firestore.Update{{Path: "config", Value: structs.Map(config)}})
+// maxCommentsLength is how long comments for partners can be
+const maxCommentsLength = 500
+
+// validateComments keeps comments for partners to maxCommentsLength
+func validateComments(comments string) error {
+	if len(comments) > maxCommentsLength {
+		return fmt.Errorf("Please keep your comments under %d characters.", maxCommentsLength)
+	}
+	return nil
+}
+
+// parseConfigForm checks each field of a submitted config page against what
+// it can be, noting what's wrong with any that don't fit
+func parseConfigForm(form url.Values) configPage {
+	errs := make(map[string]string)
+	oneOf := func(field string, choices []string, what string) string {
+		value := form.Get(field)
+		if !contains(choices, value) {
+			errs[field] = fmt.Sprintf("Please choose %s.", what)
+		}
+		return value
+	}
+	someOf := func(field string, choices []string, what string) []string {
+		values := []string{}
+		for _, value := range form[field] {
+			if !contains(choices, value) {
+				errs[field] = fmt.Sprintf("%q isn't a %s I know.", value, what)
+			}
+			values = append(values, value)
+		}
+		return values
+	}
+
+	page := configPage{
+		TopicSelection:    oneOf("topicSelection", topicSelectionOptions, "which topics you'd like"),
+		QuestionSelection: oneOf("questionSelection", questionSelectionOptions, "who picks your pairing questions"),
+	}
+
+	topics := someOf("topics", topicOptions, "topic")
+	switch {
+	case page.TopicSelection == "randomTopic":
+		topics = []string{}
+	case page.TopicSelection == "manualTopic" && len(topics) == 0:
+		errs["topics"] = "Please pick at least one topic, or choose all topics."
+	}
+
+	page.SoloTime = strings.TrimSpace(form.Get("soloTime"))
+	soloTime := defaultSoloTime
+	if page.SoloTime != "" {
+		parsed, err := parseSoloTime(page.SoloTime)
+		if err != nil {
+			errs["soloTime"] = err.Error()
+		}
+		soloTime = parsed
+	}
+
+	timezone := strings.TrimSpace(form.Get("timezone"))
+	if timezone == "" {
+		timezone = defaultTimezone
+	} else if err := validateTimezone(timezone); err != nil {
+		errs["timezone"] = err.Error()
+	}
+
+	soloDifficulty := someOf("soloDifficulty", difficultyOptions, "difficulty")
+	if len(soloDifficulty) == 0 {
+		errs["soloDifficulty"] = "Please pick at least one difficulty."
+	}
+	pairingDifficulty := someOf("pairingDifficulty", difficultyOptions, "difficulty")
+	if len(pairingDifficulty) == 0 {
+		errs["pairingDifficulty"] = "Please pick at least one difficulty."
+	}
+
+	comments := strings.TrimSpace(form.Get("comments"))
+	if comments == "" {
+		comments = defaultUserConfig().Comments
+	} else if err := validateComments(comments); err != nil {
+		errs["comments"] = err.Error()
+	}
+
+	page.Config = UserConfig{
+		comments,
+		oneOf("environment", environmentOptions, "an environment"),
+		oneOf("experience", difficultyOptions, "the level you're comfortable with"),
+		oneOf("questionList", problemSetOptions, "where your questions come from"),
+		form.Get("sequential") == "sequential",
+		topics,
+		page.TopicSelection == "weakTopic",
+		someOf("soloDays", dayOptions, "day"),
+		soloTime,
+		timezone,
+		soloDifficulty,
+		form.Get("adaptiveDifficulty") == "adaptiveDifficulty",
+		pairingDifficulty,
+		page.QuestionSelection == "manualQuestion",
+	}
+
+	// show them what they sent rather than what it'd be stored as
+	if errs["timezone"] != "" {
+		page.Config.Timezone = form.Get("timezone")
+	}
+	if len(errs) > 0 {
+		page.Errors = errs
+	}
+	return page
 }
@@ -5,14 +5,12 @@ import (
 	"log"
 	"net/http"
 	"time"
-
-	"cloud.google.com/go/firestore"
 )
 
 const githubURL = "https://github.com/cdkini/AlgoBot"
 
-// Cron makes matches for pairing, and messages those people to notify them of their match
-// it runs once per day at 8am (it's triggered with app engine's Cron service)
+// Cron runs whichever scheduled jobs are due, along with any it missed
+// it's triggered every few minutes with app engine's Cron service; see jobs for what runs when
 func Cron(w http.ResponseWriter, r *http.Request) {
 	// Check that the request is originating from within app engine
 	// https://cloud.google.com/appengine/docs/flexible/go/scheduling-jobs-with-cron-yaml#validating_cron_requests
@@ -21,24 +19,12 @@ func Cron(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// setting up database connection
 	ctx := context.Background()
-	var err error
-	client, err = firestore.NewClient(ctx, "algobot-308118")
-	defer client.Close()
 
+	zulip, err := newZulipClient(store, ctx)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	switch hour := time.Now().Hour(); hour {
-	case 9:
-		MessagePairs(client, ctx)
-	case 11:
-		MessageSolo(client, ctx)
-	case 13:
-		PostDaily(client, ctx)
-	default:
-		log.Fatal("Something is up with cron; this shouldn't be running! Check out your YAML")
-	}
+	runDueJobs(store, zulip, time.Now(), ctx)
 }
@@ -1,12 +1,17 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestIsValidSoFar(t *testing.T) {
+func TestIsValidMatch(t *testing.T) {
 	a := Recurser{Id: "A", Config: UserConfig{
 		Experience:        "easy",
 		PairingDifficulty: []string{"easy"},
@@ -29,27 +34,27 @@ func TestIsValidSoFar(t *testing.T) {
 	}}
 
 	table := []struct {
-		input []Recurser
+		input [2]Recurser
 		want  bool
 	}{
 		{
-			input: []Recurser{a, d},
+			input: [2]Recurser{a, d},
 			want:  false,
 		},
 		{
-			input: []Recurser{b, c},
+			input: [2]Recurser{b, c},
 			want:  true,
 		},
 		{
-			input: []Recurser{e, d, c},
+			input: [2]Recurser{d, c},
 			want:  true,
 		},
 		{
-			input: []Recurser{a, b, c, d},
+			input: [2]Recurser{c, d},
 			want:  true,
 		},
 		{
-			input: []Recurser{b, c, a, e},
+			input: [2]Recurser{a, e},
 			want:  false,
 		},
 	}
@@ -58,7 +63,7 @@ func TestIsValidSoFar(t *testing.T) {
 		name := fmt.Sprintf("Test %v", i)
 		t.Run(name, func(t *testing.T) {
 			for i := 0; i < 100; i++ {
-				got := isValidSoFar(test.input)
+				got := isValidMatch(test.input[0], test.input[1])
 
 				if got != test.want {
 					t.Errorf("%s: Expected %v, got %v", name, test.want, got)
@@ -94,10 +99,15 @@ func TestDetermineBestPath(t *testing.T) {
 		Experience:        "hard",
 		PairingDifficulty: []string{"medium, hard"},
 	}}
+	g := Recurser{Id: "G", Config: UserConfig{
+		Experience:        "hard",
+		PairingDifficulty: []string{"medium", "hard"},
+	}}
 
 	table := []struct {
 		input      []Recurser
 		validPairs int
+		triad      bool
 	}{
 		{
 			input:      []Recurser{},
@@ -135,16 +145,32 @@ func TestDetermineBestPath(t *testing.T) {
 			input:      []Recurser{a, b, c, d, e, f},
 			validPairs: 3,
 		},
+		// B, C and D can all pair with each other, so no one is left out
+		{
+			input:      []Recurser{b, c, d},
+			validPairs: 0,
+			triad:      true,
+		},
+		{
+			input:      []Recurser{a, b, c, d, e, f, g},
+			validPairs: 2,
+			triad:      true,
+		},
 	}
 
 	for i, test := range table {
 		name := fmt.Sprintf("Test %v", i)
 		t.Run(name, func(t *testing.T) {
 			for i := 0; i < 100; i++ {
-				got, _ := determineBestPath(test.input)
+				got, _ := determineBestPath(test.input, matchHistory{})
+
+				if len(got.order)+len(got.triad) != len(test.input) {
+					t.Errorf("%s: Expected length %v, got length %v", name, len(test.input), len(got.order)+len(got.triad))
+					break
+				}
 
-				if len(got.order) != len(test.input) {
-					t.Errorf("%s: Expected length %v, got length %v", name, len(test.input), len(got.order))
+				if (len(got.triad) == 3) != test.triad {
+					t.Errorf("%s: Expected triad %v, got %v", name, test.triad, got.triad)
 					break
 				}
 
@@ -157,6 +183,355 @@ func TestDetermineBestPath(t *testing.T) {
 	}
 }
 
+func TestSetMatcher(t *testing.T) {
+	defer func() { pairingMatcher = weightedMatching }()
+
+	for _, name := range []string{"weighted", "roommates"} {
+		if err := SetMatcher(name); err != nil {
+			t.Errorf("Expected %v to be a matcher, got %v", name, err)
+		}
+	}
+	if err := SetMatcher("random"); err == nil {
+		t.Errorf("Expected an unknown matcher to be rejected")
+	}
+}
+
+func TestStableRoommates(t *testing.T) {
+	table := []struct {
+		prefs   [][]int
+		partner []int
+	}{
+		// the example from https://en.wikipedia.org/wiki/Stable_roommates_problem, zero-indexed
+		{
+			prefs: [][]int{
+				{2, 3, 1, 5, 4},
+				{5, 4, 3, 0, 2},
+				{1, 3, 4, 0, 5},
+				{4, 1, 2, 5, 0},
+				{2, 0, 1, 3, 5},
+				{4, 0, 2, 3, 1},
+			},
+			partner: []int{5, 3, 4, 1, 2, 0},
+		},
+		// incomplete lists: 3 is only acceptable to 0 and 2 has no one at all
+		{
+			prefs: [][]int{
+				{1, 3},
+				{0},
+				{},
+				{0},
+			},
+			partner: []int{1, 0, -1, -1},
+		},
+		// everyone wants the next person in the cycle, so there is no stable matching
+		{
+			prefs: [][]int{
+				{1, 2, 3},
+				{2, 0, 3},
+				{0, 1, 3},
+				{0, 1, 2},
+			},
+			partner: nil,
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		partner, culprit := stableRoommates(test.prefs)
+
+		if test.partner == nil {
+			if culprit == -1 {
+				t.Errorf("%s: Expected no stable matching, got %v", name, partner)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(partner, test.partner) {
+			t.Errorf("%s: Expected %v, got %v (culprit %v)", name, test.partner, partner, culprit)
+		}
+	}
+}
+
+func TestStableMatchingIsStable(t *testing.T) {
+	experiences := []string{"easy", "medium", "hard"}
+	difficulties := [][]string{{"easy"}, {"easy", "medium"}, {"medium"}, {"medium", "hard"}, {"hard"}}
+	environments := []string{"leetcode", "replit", "googleDocs"}
+	r := rand.New(rand.NewSource(1))
+
+	// a whole batch's worth of people should be matched without any pair
+	// preferring each other over who they were given
+	for round := 0; round < 20; round++ {
+		pool := make([]Recurser, 60)
+		for i := range pool {
+			pool[i] = Recurser{Id: fmt.Sprint(i), Config: UserConfig{
+				Experience:        experiences[r.Intn(len(experiences))],
+				PairingDifficulty: difficulties[r.Intn(len(difficulties))],
+				Environment:       environments[r.Intn(len(environments))],
+			}}
+		}
+
+		prefs := buildPreferences(pool, matchHistory{})
+		partner, culprit := stableRoommates(prefs)
+		if culprit != -1 {
+			continue
+		}
+
+		prefers := func(i int, j int) bool {
+			if partner[i] == -1 {
+				return true
+			}
+			for _, k := range prefs[i] {
+				if k == j {
+					return true
+				}
+				if k == partner[i] {
+					return false
+				}
+			}
+			return false
+		}
+
+		for i := range pool {
+			if partner[i] != -1 && partner[partner[i]] != i {
+				t.Fatalf("Round %v: %v is matched with %v but not the other way around", round, i, partner[i])
+			}
+			for _, j := range prefs[i] {
+				if j != partner[i] && prefers(i, j) && prefers(j, i) {
+					t.Errorf("Round %v: %v and %v would rather be with each other", round, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestStableMatching(t *testing.T) {
+	a := Recurser{Id: "A", Config: UserConfig{Experience: "easy", PairingDifficulty: []string{"easy"}}}
+	b := Recurser{Id: "B", Config: UserConfig{Experience: "medium", PairingDifficulty: []string{"easy", "medium"}}}
+	c := Recurser{Id: "C", Config: UserConfig{Experience: "medium", PairingDifficulty: []string{"easy", "medium"}}}
+	d := Recurser{Id: "D", Config: UserConfig{Experience: "medium", PairingDifficulty: []string{"medium"}}}
+
+	// B and C share a config so they prefer each other over anyone else,
+	// which leaves A and D (who can't be matched) without a partner
+	pairs, unmatched := stableMatching([]Recurser{a, b, c, d}, matchHistory{})
+	if len(pairs) != 1 || len(unmatched) != 2 {
+		t.Errorf("Expected B and C to pair and A and D to sit out, got %v and %v", pairs, unmatched)
+	}
+
+	// as long as B and C paired recently they're kept apart
+	history := matchHistory{
+		now:      time.Now(),
+		lookback: 14 * 24 * time.Hour,
+		lastPaired: map[string]map[string]time.Time{
+			"B": {"C": time.Now().AddDate(0, 0, -1)},
+			"C": {"B": time.Now().AddDate(0, 0, -1)},
+		},
+	}
+	pairs, _ = stableMatching([]Recurser{a, b, c, d}, history)
+	for _, pair := range pairs {
+		if history.isRecentRepeat(pair[0], pair[1]) {
+			t.Errorf("Expected recent partners to be kept apart, got %v and %v", pair[0].Id, pair[1].Id)
+		}
+	}
+	if len(pairs) != 2 {
+		t.Errorf("Expected A and D to pair with B and C instead, got %v", pairs)
+	}
+}
+
+func TestMaxWeightMatching(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// compare against trying every matching on lots of small random graphs
+	for round := 0; round < 300; round++ {
+		n := 1 + r.Intn(9)
+		var edges []weightedEdge
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if r.Intn(3) != 0 {
+					edges = append(edges, weightedEdge{i, j, 1 + r.Intn(20)})
+				}
+			}
+		}
+
+		for _, maxCardinality := range []bool{false, true} {
+			name := fmt.Sprintf("Round %v (maxCardinality %v)", round, maxCardinality)
+			mate := maxWeightMatching(n, edges, maxCardinality)
+
+			size, weight := 0, 0
+			for _, e := range edges {
+				if mate[e.i] == e.j {
+					size++
+					weight += e.weight
+				}
+			}
+			for v, w := range mate {
+				if w != -1 && mate[w] != v {
+					t.Fatalf("%s: %v is matched with %v but not the other way around", name, v, w)
+				}
+			}
+
+			wantSize, wantWeight := bestMatching(n, edges, make([]bool, n), maxCardinality)
+			if maxCardinality && size != wantSize {
+				t.Errorf("%s: Expected %v pairs, got %v", name, wantSize, size)
+			}
+			if weight != wantWeight {
+				t.Errorf("%s: Expected weight %v, got %v", name, wantWeight, weight)
+			}
+		}
+	}
+}
+
+// bestMatching brute forces the size and weight of the best matching among
+// the vertices not yet used
+func bestMatching(n int, edges []weightedEdge, used []bool, maxCardinality bool) (int, int) {
+	v := 0
+	for v < n && used[v] {
+		v++
+	}
+	if v == n {
+		return 0, 0
+	}
+
+	// either v stays unmatched...
+	used[v] = true
+	bestSize, bestWeight := bestMatching(n, edges, used, maxCardinality)
+
+	// ...or it's matched with one of its free neighbours
+	for _, e := range edges {
+		w := -1
+		if e.i == v && !used[e.j] {
+			w = e.j
+		} else if e.j == v && !used[e.i] {
+			w = e.i
+		}
+		if w == -1 {
+			continue
+		}
+
+		used[w] = true
+		size, weight := bestMatching(n, edges, used, maxCardinality)
+		used[w] = false
+		size, weight = size+1, weight+e.weight
+
+		if (maxCardinality && size > bestSize) ||
+			((!maxCardinality || size == bestSize) && weight > bestWeight) {
+			bestSize, bestWeight = size, weight
+		}
+	}
+	used[v] = false
+
+	return bestSize, bestWeight
+}
+
+func TestWeightedMatchingPrefersNewPartners(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	config := UserConfig{
+		Experience:        "medium",
+		Environment:       "leetcode",
+		PairingDifficulty: []string{"easy", "medium"},
+	}
+	a := Recurser{Id: "A", Config: config}
+	b := Recurser{Id: "B", Config: config}
+	c := Recurser{Id: "C", Config: config}
+	d := Recurser{Id: "D", Config: config}
+	lastMonth := now.AddDate(0, -1, 0)
+	yesterday := now.AddDate(0, 0, -1)
+
+	table := []struct {
+		pool    []Recurser
+		history matchHistory
+		want    map[string]string
+	}{
+		// A has paired with B and D before, and C with D
+		{
+			pool: []Recurser{a, b, c, d},
+			history: testMatchHistory(now, map[[2]string]time.Time{
+				{"A", "B"}: lastMonth, {"A", "D"}: lastMonth, {"C", "D"}: lastMonth,
+			}),
+			want: map[string]string{"A": "C", "B": "D"},
+		},
+		// B and C have been waiting a week, so A is the one left out
+		{
+			pool: []Recurser{
+				a,
+				{Id: "B", QueuedAt: now.AddDate(0, 0, -7), Config: config},
+				{Id: "C", QueuedAt: now.AddDate(0, 0, -8), Config: config},
+			},
+			history: testMatchHistory(now, nil),
+			want:    map[string]string{"B": "C"},
+		},
+		// C is a worse fit than B and has even paired with A before, but
+		// went unmatched yesterday so they come first
+		{
+			pool: []Recurser{
+				a,
+				b,
+				{Id: "C", UnmatchedDays: 1, Config: UserConfig{
+					Experience:        "easy",
+					Environment:       "replit",
+					PairingDifficulty: []string{"easy"},
+				}},
+			},
+			history: testMatchHistory(now, map[[2]string]time.Time{{"A", "C"}: lastMonth}),
+			want:    map[string]string{"B": "C"},
+		},
+		// A and B paired yesterday, so they aren't matched again...
+		{
+			pool:    []Recurser{a, b},
+			history: testMatchHistory(now, map[[2]string]time.Time{{"A", "B"}: yesterday}),
+			want:    map[string]string{},
+		},
+		// ...unless one of them asks for it
+		{
+			pool:    []Recurser{a, {Id: "B", RematchWith: []string{"A"}, Config: config}},
+			history: testMatchHistory(now, map[[2]string]time.Time{{"A", "B"}: yesterday}),
+			want:    map[string]string{"A": "B"},
+		},
+		// an older pairing is fine, if not preferred
+		{
+			pool:    []Recurser{a, b},
+			history: testMatchHistory(now, map[[2]string]time.Time{{"A", "B"}: lastMonth}),
+			want:    map[string]string{"A": "B"},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		for round := 0; round < 10; round++ {
+			pool := append([]Recurser{}, test.pool...)
+			r := rand.New(rand.NewSource(int64(round)))
+			r.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+			pairs, _ := weightedMatching(pool, test.history)
+			got := make(map[string]string)
+			for _, pair := range pairs {
+				first, second := pair[0].Id, pair[1].Id
+				if first > second {
+					first, second = second, first
+				}
+				got[first] = second
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+				break
+			}
+		}
+	}
+}
+
+// testMatchHistory is the history of a pool where each pair last met at the
+// given time, with the default lookback
+func testMatchHistory(now time.Time, lastPaired map[[2]string]time.Time) matchHistory {
+	history := matchHistory{now: now, lookback: 14 * 24 * time.Hour, lastPaired: make(map[string]map[string]time.Time)}
+	for pair, when := range lastPaired {
+		for _, ids := range [][2]string{pair, {pair[1], pair[0]}} {
+			if history.lastPaired[ids[0]] == nil {
+				history.lastPaired[ids[0]] = make(map[string]time.Time)
+			}
+			history.lastPaired[ids[0]][ids[1]] = when
+		}
+	}
+	return history
+}
+
 func TestDeterminePairs(t *testing.T) {
 	a := Recurser{Id: "A", Config: UserConfig{
 		Experience:        "easy",
@@ -189,34 +564,35 @@ func TestDeterminePairs(t *testing.T) {
 		notPairedList []Recurser
 	}{
 		{
-			input:         Path{[]Recurser{a, b}, 1},
+			input:         Path{[]Recurser{a, b}, 1, nil},
 			pairedList:    []Recurser{a, b},
 			notPairedList: []Recurser{},
 		},
 		{
-			input:         Path{[]Recurser{a, d}, 0},
+			input:         Path{[]Recurser{a, d}, 0, nil},
 			pairedList:    []Recurser{},
 			notPairedList: []Recurser{a, d},
 		},
 		{
-			input:         Path{[]Recurser{a, c, e}, 1},
+			input:         Path{[]Recurser{a, c, e}, 1, nil},
 			pairedList:    []Recurser{a, c},
 			notPairedList: []Recurser{e},
 		},
 		{
-			input:         Path{[]Recurser{a, b, c, d}, 2},
+			input:         Path{[]Recurser{a, b, c, d}, 2, nil},
 			pairedList:    []Recurser{a, b, c, d},
 			notPairedList: []Recurser{},
 		},
 		{
-			input:         Path{[]Recurser{a, b, c, d, e}, 2},
+			input:         Path{[]Recurser{a, b, c, d, e}, 2, nil},
 			pairedList:    []Recurser{a, b, c, d},
 			notPairedList: []Recurser{e},
 		},
 		{
-			input:         Path{[]Recurser{a, d, b, f}, 0},
-			pairedList:    []Recurser{b, f},
-			notPairedList: []Recurser{a, d},
+			// b and f could pair but the matcher didn't choose to
+			input:         Path{[]Recurser{a, d, b, f}, 0, nil},
+			pairedList:    []Recurser{},
+			notPairedList: []Recurser{a, d, b, f},
 		},
 	}
 
@@ -239,3 +615,921 @@ func TestDeterminePairs(t *testing.T) {
 		})
 	}
 }
+
+// soloHourToday is today at the hour solo questions go out by default
+func soloHourToday() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 11, 0, 0, 0, time.UTC)
+}
+
+func TestMessageSolo(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	// make sure today is a solo day for everyone, whatever day the test runs on
+	everyDay := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	for _, id := range []string{"1", "2", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		recurser.Config.SoloDays = everyDay
+		s.PutRecurser(ctx, recurser)
+	}
+
+	now := soloHourToday()
+	MessageSolo(s, zulip.client(), now, ctx)
+
+	// Alan only matches Two Sum (easy, array, top100Liked)
+	twoSum := Question{Id: 1, URL: "https://leetcode.com/problems/two-sum"}
+	if got := zulip.privateMessages("alan@example.com"); !reflect.DeepEqual(got, []string{fmtSoloMessage(&twoSum, false)}) {
+		t.Errorf("Expected Alan to get Two Sum, got %v", got)
+	}
+	// sessions are recorded at the time the run was for, not when it ran
+	if got, _ := s.SoloSessions(ctx, "3"); len(got) != 1 || got[0].Question != 1 || !got[0].TimeStamp.Equal(now) {
+		t.Errorf("Expected Alan's session to be recorded at %v, got %v", now, got)
+	}
+
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 1 {
+		t.Errorf("Expected Ada to get one question, got %v", got)
+	}
+
+	// Grace skipped today, so she gets nothing but is unskipped for tomorrow
+	if got := zulip.privateMessages("grace@example.com"); len(got) != 0 {
+		t.Errorf("Expected Grace to be skipped, got %v", got)
+	}
+	if grace, _, _ := s.GetRecurser(ctx, "2"); grace.IsSkippingTomorrow {
+		t.Errorf("Expected Grace's skip to be reset")
+	}
+}
+
+func TestMessageSoloRetry(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	everyDay := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	for _, id := range []string{"1", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		recurser.Config.SoloDays = everyDay
+		s.PutRecurser(ctx, recurser)
+	}
+
+	// Ada's question is turned away, so the run fails and is retried
+	now := soloHourToday()
+	zulip.reject("ada@example.com")
+	if err := runJob(findJob("solo"), s, zulip.client(), now, ctx); err == nil {
+		t.Errorf("Expected the solo job to fail when a question is turned away")
+	}
+	if err := runJob(findJob("solo"), s, zulip.client(), now, ctx); err != nil {
+		t.Errorf("Expected the retry to succeed, got %v", err)
+	}
+
+	// the retry only finishes what was left
+	for _, id := range []string{"1", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		if got := zulip.privateMessages(recurser.Email); len(got) != 1 {
+			t.Errorf("Expected %s to get one question, got %v", recurser.Name, got)
+		}
+		if got, _ := s.SoloSessions(ctx, id); len(got) != 1 {
+			t.Errorf("Expected one solo session for %s, got %v", recurser.Name, got)
+		}
+	}
+}
+
+func TestMessageSoloSkipDates(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	now := soloHourToday()
+	yesterday := now.AddDate(0, 0, -1).Format(dateLayout)
+	today := now.Format(dateLayout)
+	tomorrow := now.AddDate(0, 0, 1).Format(dateLayout)
+
+	everyDay := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	for _, id := range []string{"1", "2", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		recurser.Config.SoloDays = everyDay
+		recurser.IsSkippingTomorrow = false
+		s.PutRecurser(ctx, recurser)
+	}
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.SkipDates = []string{yesterday, today, tomorrow}
+	s.PutRecurser(ctx, ada)
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.SkipDates = []string{tomorrow}
+	s.PutRecurser(ctx, alan)
+
+	MessageSolo(s, zulip.client(), now, ctx)
+
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 0 {
+		t.Errorf("Expected Ada to skip today, got %v", got)
+	}
+	if got, _, _ := s.GetRecurser(ctx, "1"); !reflect.DeepEqual(got.SkipDates, []string{tomorrow}) {
+		t.Errorf("Expected Ada's past skip dates to be forgotten, got %v", got.SkipDates)
+	}
+	if got := zulip.privateMessages("alan@example.com"); len(got) != 1 {
+		t.Errorf("Expected Alan to get today's question, got %v", got)
+	}
+	if got, _, _ := s.GetRecurser(ctx, "3"); !reflect.DeepEqual(got.SkipDates, []string{tomorrow}) {
+		t.Errorf("Expected Alan to still be skipping tomorrow, got %v", got.SkipDates)
+	}
+}
+
+func TestMessageSoloPauses(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	now := soloHourToday()
+	everyDay := []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	for _, id := range []string{"1", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		recurser.Config.SoloDays = everyDay
+		s.PutRecurser(ctx, recurser)
+	}
+
+	// Ada is away until tomorrow, Alan's break is over
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Pauses = []Pause{{Start: now.AddDate(0, 0, -3).Format(dateLayout), End: now.Format(dateLayout)}}
+	s.PutRecurser(ctx, ada)
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Pauses = []Pause{{Start: now.AddDate(0, 0, -3).Format(dateLayout), End: now.AddDate(0, 0, -1).Format(dateLayout)}}
+	s.PutRecurser(ctx, alan)
+
+	MessageSolo(s, zulip.client(), now, ctx)
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 0 {
+		t.Errorf("Expected paused Ada to get nothing, got %v", got)
+	}
+	if got := zulip.privateMessages("alan@example.com"); len(got) != 1 {
+		t.Errorf("Expected Alan to get today's question, got %v", got)
+	}
+
+	MessageSolo(s, zulip.client(), now.AddDate(0, 0, 1), ctx)
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 1 {
+		t.Errorf("Expected Ada to get a question once she's back, got %v", got)
+	}
+}
+
+func TestMessageSoloRepeat(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Config.SoloDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	s.PutRecurser(ctx, alan)
+	s.AppendSoloSession(ctx, "3", SoloSession{Question: 1, TimeStamp: time.Now().AddDate(0, 0, -7)})
+
+	MessageSolo(s, zulip.client(), soloHourToday(), ctx)
+
+	// Two Sum is the only question that fits, so Alan gets it again with a notice
+	twoSum := Question{Id: 1, URL: "https://leetcode.com/problems/two-sum"}
+	if got := zulip.privateMessages("alan@example.com"); !reflect.DeepEqual(got, []string{fmtSoloMessage(&twoSum, true)}) {
+		t.Errorf("Expected Alan to get Two Sum again, got %v", got)
+	}
+	if !strings.Contains(fmtSoloMessage(&twoSum, true), "You've already had every question") {
+		t.Errorf("Expected a notice that the question is a repeat")
+	}
+}
+
+func TestSoloDue(t *testing.T) {
+	// a Monday, when it's 8am in New York and 1am on Tuesday in Auckland
+	now := time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		config UserConfig
+		want   bool
+	}{
+		{UserConfig{SoloDays: []string{"mon"}}, false},
+		{UserConfig{SoloDays: []string{"mon"}, SoloTime: "12:00"}, true},
+		{UserConfig{SoloDays: []string{"mon"}, SoloTime: "08:00", Timezone: "America/New_York"}, true},
+		{UserConfig{SoloDays: []string{"tue"}, SoloTime: "08:00", Timezone: "America/New_York"}, false},
+		{UserConfig{SoloDays: []string{"tue"}, SoloTime: "01:00", Timezone: "Pacific/Auckland"}, true},
+		{UserConfig{SoloDays: []string{"mon"}, SoloTime: "01:00", Timezone: "Pacific/Auckland"}, false},
+		// a time zone that can't be loaded falls back to UTC
+		{UserConfig{SoloDays: []string{"mon"}, SoloTime: "12:00", Timezone: "Mars/Olympus"}, true},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := soloDue(Recurser{Config: test.config}, now)
+		if got != test.want {
+			t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestMessageSoloTimezones(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Config.SoloDays = []string{"mon"}
+	ada.Config.SoloTime = "08:00"
+	ada.Config.Timezone = "America/New_York"
+	s.PutRecurser(ctx, ada)
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Config.SoloDays = []string{"tue"}
+	alan.Config.SoloTime = "01:00"
+	alan.Config.Timezone = "Pacific/Auckland"
+	s.PutRecurser(ctx, alan)
+	grace, _, _ := s.GetRecurser(ctx, "2")
+	grace.Config.SoloDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	s.PutRecurser(ctx, grace)
+
+	// 8am Monday in New York and 1am Tuesday in Auckland
+	MessageSolo(s, zulip.client(), time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC), ctx)
+
+	for _, email := range []string{"ada@example.com", "alan@example.com"} {
+		if got := zulip.privateMessages(email); len(got) != 1 {
+			t.Errorf("Expected %s to get one question, got %v", email, got)
+		}
+	}
+	// Grace's question isn't due yet, so she's still skipping it
+	if got, _, _ := s.GetRecurser(ctx, "2"); !got.IsSkippingTomorrow {
+		t.Errorf("Expected Grace to still be skipping her next question")
+	}
+
+	MessageSolo(s, zulip.client(), time.Date(2026, time.October, 20, 11, 0, 0, 0, time.UTC), ctx)
+
+	if got := zulip.privateMessages("grace@example.com"); len(got) != 0 {
+		t.Errorf("Expected Grace to be skipped, got %v", got)
+	}
+	if got, _, _ := s.GetRecurser(ctx, "2"); got.IsSkippingTomorrow {
+		t.Errorf("Expected Grace's skip to be reset")
+	}
+	for _, email := range []string{"ada@example.com", "alan@example.com"} {
+		if got := zulip.privateMessages(email); len(got) != 1 {
+			t.Errorf("Expected %s to get nothing more, got %v", email, got)
+		}
+	}
+}
+
+func TestReviewSchedule(t *testing.T) {
+	start := time.Date(2026, 10, 1, 7, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time {
+		return start.AddDate(0, 0, n)
+	}
+
+	table := []struct {
+		sessions []SoloSession
+		want     *reviewCard
+	}{
+		{
+			sessions: []SoloSession{{Question: 1, TimeStamp: day(0)}},
+			want:     nil,
+		},
+		{
+			sessions: []SoloSession{{Question: 1, TimeStamp: day(0), Rating: 4}},
+			want:     &reviewCard{question: 1, repetitions: 1, interval: 1, ease: 2.5, lastRating: 4, lastSeen: day(0), due: day(1)},
+		},
+		{
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Rating: 5},
+				{Question: 1, TimeStamp: day(1), Rating: 5},
+				{Question: 1, TimeStamp: day(7), Rating: 5},
+			},
+			want: &reviewCard{question: 1, repetitions: 3, interval: 16, ease: 2.8, lastRating: 5, lastSeen: day(7), due: day(23)},
+		},
+		{
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Rating: 5},
+				{Question: 1, TimeStamp: day(1), Rating: 1},
+			},
+			want: &reviewCard{question: 1, repetitions: 0, interval: 1, ease: 2.06, lastRating: 1, lastSeen: day(1), due: day(2)},
+		},
+		{
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Rating: 1},
+				{Question: 1, TimeStamp: day(1), Rating: 1},
+				{Question: 1, TimeStamp: day(2), Rating: 1},
+			},
+			want: &reviewCard{question: 1, repetitions: 0, interval: 1, ease: minEase, lastRating: 1, lastSeen: day(2), due: day(3)},
+		},
+		{
+			// an unrated review keeps the interval but restarts the clock
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Rating: 3},
+				{Question: 3, TimeStamp: day(1), Rating: 5},
+				{Question: 1, TimeStamp: day(2), Review: true},
+			},
+			want: &reviewCard{question: 1, repetitions: 1, interval: 1, ease: 2.36, lastRating: 3, lastSeen: day(2), due: day(3)},
+		},
+		{
+			// without a rating, solving it counts as a 4 and giving up as a 1
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Outcome: outcomeSolved},
+				{Question: 1, TimeStamp: day(1), Outcome: outcomeGaveUp, Review: true},
+			},
+			want: &reviewCard{question: 1, repetitions: 0, interval: 1, ease: 1.96, lastOutcome: outcomeGaveUp, lastSeen: day(1), due: day(2)},
+		},
+		{
+			// but an explicit rating wins
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: day(0), Outcome: outcomeGaveUp, Rating: 3},
+			},
+			want: &reviewCard{question: 1, repetitions: 1, interval: 1, ease: 2.36, lastRating: 3, lastOutcome: outcomeGaveUp, lastSeen: day(0), due: day(1)},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := reviewSchedule(test.sessions)[1]
+		if got != nil {
+			got.ease = math.Round(got.ease*100) / 100
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Expected %+v, got %+v", name, test.want, got)
+		}
+	}
+}
+
+func TestDueReview(t *testing.T) {
+	now := time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)
+	sessions := []SoloSession{
+		{Question: 1, TimeStamp: now.AddDate(0, 0, -5), Rating: 4},
+		{Question: 3, TimeStamp: now.AddDate(0, 0, -4), Rating: 2},
+		{Question: 4, TimeStamp: now.AddDate(0, 0, -3)},
+		{Question: 26, TimeStamp: now.AddDate(0, 0, -2), Rating: 5},
+	}
+
+	// 1 was due four days ago, 3 three days ago and 26 is due tomorrow
+	if card, ok := dueReview(sessions, now); !ok || card.question != 1 {
+		t.Errorf("Expected question 1 to be the most overdue, got %+v", card)
+	}
+
+	// reviews take turns with new questions
+	sessions = append(sessions, SoloSession{Question: 1, TimeStamp: now, Review: true})
+	if card, ok := dueReview(sessions, now); ok {
+		t.Errorf("Expected no review right after a review, got %+v", card)
+	}
+}
+
+func TestMessageSoloReview(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Config.SoloDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	s.PutRecurser(ctx, alan)
+	now := soloHourToday()
+	lastSeen := now.AddDate(0, 0, -8)
+	s.AppendSoloSession(ctx, "3", SoloSession{Question: 1, TimeStamp: lastSeen, Rating: 4})
+
+	MessageSolo(s, zulip.client(), now, ctx)
+
+	twoSum := Question{Id: 1, URL: "https://leetcode.com/problems/two-sum"}
+	card := &reviewCard{lastRating: 4, lastSeen: lastSeen}
+	got := zulip.privateMessages("alan@example.com")
+	if len(got) != 1 || got[0] != fmtReviewMessage(&twoSum, card, now) {
+		t.Errorf("Expected Alan to review Two Sum, got %v", got)
+	}
+	if !strings.Contains(got[0], "You rated it a 4/5 when you saw it 8 days ago") {
+		t.Errorf("Expected the review to say when Alan last saw it, got %q", got[0])
+	}
+	sessions, _ := s.SoloSessions(ctx, "3")
+	if len(sessions) != 2 || !sessions[1].Review || sessions[1].Question != 1 {
+		t.Errorf("Expected the review to be recorded, got %v", sessions)
+	}
+}
+
+func TestMessagePairs(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	MessagePairs(s, zulip.client(), time.Now(), ctx)
+
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); !reflect.DeepEqual(got, []string{botMessages.Matched}) {
+		t.Errorf("Expected Ada and Grace to be matched, got %v", got)
+	}
+
+	// Ada interviews Grace, who gets hard questions on trees or design
+	serialize := Question{Id: 297, URL: "https://leetcode.com/problems/serialize-and-deserialize-binary-tree"}
+	grace, _, _ := s.GetRecurser(ctx, "2")
+	if got := zulip.privateMessages("ada@example.com"); !reflect.DeepEqual(got, []string{fmtInterviewerMessage(&serialize, grace)}) {
+		t.Errorf("Expected Ada to prepare Serialize and Deserialize Binary Tree, got %v", got)
+	}
+	if got := zulip.privateMessages("grace@example.com"); len(got) != 1 {
+		t.Errorf("Expected Grace to get interviewer instructions, got %v", got)
+	}
+
+	sessions, _ := s.PairingSessions(ctx, "2")
+	if len(sessions) != 1 || sessions[0].Interviewer != "1" || sessions[0].Question != 297 {
+		t.Errorf("Expected Grace's session with Ada to be recorded, got %v", sessions)
+	}
+
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 0 {
+		t.Errorf("Expected the queue to be emptied, got %v", queue)
+	}
+}
+
+func TestMessagePairsRetry(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Ada and Grace's match is turned away, so the run fails and is retried
+	zulip.reject("ada@example.com", "grace@example.com")
+	if err := runJob(findJob("pairs"), s, zulip.client(), now, ctx); err == nil {
+		t.Errorf("Expected the pairs job to fail when a match is turned away")
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 2 {
+		t.Errorf("Expected both to stay in the queue, got %v", queue)
+	}
+
+	if err := runJob(findJob("pairs"), s, zulip.client(), now, ctx); err != nil {
+		t.Errorf("Expected the retry to succeed, got %v", err)
+	}
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); !reflect.DeepEqual(got, []string{botMessages.Matched}) {
+		t.Errorf("Expected Ada and Grace to be matched once, got %v", got)
+	}
+	for _, id := range []string{"1", "2"} {
+		if got, _ := s.PairingSessions(ctx, id); len(got) != 1 {
+			t.Errorf("Expected one pairing session for %s, got %v", id, got)
+		}
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 0 {
+		t.Errorf("Expected the queue to be emptied, got %v", queue)
+	}
+}
+
+func TestMessagePairsRetriesInterviewers(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// Ada's instructions for interviewing Grace are turned away
+	zulip.reject("ada@example.com")
+	if err := runJob(findJob("pairs"), s, zulip.client(), now, ctx); err == nil {
+		t.Errorf("Expected the pairs job to fail when instructions are turned away")
+	}
+	if got, _ := s.PairingSessions(ctx, "2"); len(got) != 0 {
+		t.Errorf("Expected no session for Grace until Ada has her instructions, got %v", got)
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 1 || queue[0].Id != "1" {
+		t.Errorf("Expected Ada to stay in the queue, got %v", queue)
+	}
+
+	// the retry sends Ada her instructions without matching anyone again
+	if err := runJob(findJob("pairs"), s, zulip.client(), now, ctx); err != nil {
+		t.Errorf("Expected the retry to succeed, got %v", err)
+	}
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); len(got) != 1 {
+		t.Errorf("Expected Ada and Grace to be matched once, got %v", got)
+	}
+	for _, email := range []string{"ada@example.com", "grace@example.com"} {
+		if got := zulip.privateMessages(email); len(got) != 1 {
+			t.Errorf("Expected %s to get their instructions once, got %v", email, got)
+		}
+	}
+	sessions, _ := s.PairingSessions(ctx, "2")
+	if len(sessions) != 1 || sessions[0].Interviewer != "1" {
+		t.Errorf("Expected Grace's session with Ada to be recorded, got %v", sessions)
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 0 {
+		t.Errorf("Expected the queue to be emptied, got %v", queue)
+	}
+}
+
+func TestMessagePairsSkipsRecentPartners(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	// Ada and Grace would match, but they paired yesterday
+	yesterday := time.Now().AddDate(0, 0, -1)
+	s.AppendPairingSession(ctx, "2", PairingSession{Interviewer: "1", Interviewee: "2", Question: 297, TimeStamp: yesterday})
+	s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "2", Interviewee: "1", Question: 104, TimeStamp: yesterday})
+
+	MessagePairs(s, zulip.client(), time.Now(), ctx)
+
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); len(got) != 0 {
+		t.Errorf("Expected Ada and Grace not to be paired again, got %v", got)
+	}
+	for _, email := range []string{"ada@example.com", "grace@example.com"} {
+		if got := zulip.privateMessages(email); !reflect.DeepEqual(got, []string{botMessages.NotMatched}) {
+			t.Errorf("Expected %v to be told they weren't matched, got %v", email, got)
+		}
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 2 {
+		t.Errorf("Expected both to stay in the queue, got %v", queue)
+	}
+}
+
+func TestMessagePairsTriad(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	// Barbara makes three in the queue, and can pair with both Ada and Grace
+	barbara := newRecurser("5", "Barbara Liskov", "barbara@example.com")
+	barbara.IsPairingTomorrow = true
+	barbara.Config.Experience = "medium"
+	barbara.Config.PairingDifficulty = []string{"easy", "medium"}
+	s.PutRecurser(ctx, barbara)
+	s.CreateSessionHistory(ctx, "5")
+
+	MessagePairs(s, zulip.client(), time.Now(), ctx)
+
+	got := zulip.privateMessages("ada@example.com", "grace@example.com", "barbara@example.com")
+	if len(got) != 1 || !strings.Contains(got[0], "interviews") {
+		t.Fatalf("Expected one message introducing the triad, got %v", got)
+	}
+
+	// everyone interviews exactly one of the others, following the same rotation
+	var rotation []string
+	interviewers := make(map[string]string)
+	for _, id := range []string{"1", "2", "5"} {
+		sessions, _ := s.PairingSessions(ctx, id)
+		if len(sessions) != 1 || sessions[0].Interviewee != id || len(sessions[0].Rotation) != 3 {
+			t.Fatalf("Expected one triad session for %v, got %v", id, sessions)
+		}
+		if rotation != nil && !reflect.DeepEqual(sessions[0].Rotation, rotation) {
+			t.Errorf("Expected the rotation %v for %v, got %v", rotation, id, sessions[0].Rotation)
+		}
+		rotation = sessions[0].Rotation
+		interviewers[sessions[0].Interviewer] = id
+	}
+	for i, id := range rotation {
+		if next := rotation[(i+1)%3]; interviewers[id] != next {
+			t.Errorf("Expected %v to interview %v, got %v", id, next, interviewers[id])
+		}
+	}
+
+	for _, email := range []string{"ada@example.com", "grace@example.com", "barbara@example.com"} {
+		if got := zulip.privateMessages(email); len(got) != 1 {
+			t.Errorf("Expected %v to get interviewer instructions, got %v", email, got)
+		}
+	}
+	if queue, _ := s.PairingQueue(ctx); len(queue) != 0 {
+		t.Errorf("Expected the queue to be emptied, got %v", queue)
+	}
+}
+
+func TestMessagePairsCountsUnmatchedDays(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	// Grace is left alone in the queue, two days running
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.IsPairingTomorrow = false
+	s.PutRecurser(ctx, ada)
+
+	now := time.Now()
+	for day := 1; day <= 2; day++ {
+		MessagePairs(s, zulip.client(), now.AddDate(0, 0, day), ctx)
+
+		grace, _, _ := s.GetRecurser(ctx, "2")
+		if !grace.IsPairingTomorrow || grace.UnmatchedDays != day {
+			t.Errorf("Day %v: Expected Grace to stay queued with %v unmatched days, got %v", day, day, grace.UnmatchedDays)
+		}
+	}
+	if got := zulip.privateMessages("grace@example.com"); len(got) != 2 || got[0] != botMessages.NotMatched {
+		t.Errorf("Expected Grace to be told she wasn't matched twice, got %v", got)
+	}
+
+	// once matched, the count starts over
+	ada.IsPairingTomorrow = true
+	s.PutRecurser(ctx, ada)
+	MessagePairs(s, zulip.client(), now.AddDate(0, 0, 3), ctx)
+
+	if grace, _, _ := s.GetRecurser(ctx, "2"); grace.IsPairingTomorrow || grace.UnmatchedDays != 0 {
+		t.Errorf("Expected Grace to leave the queue with her count reset, got %v", grace.UnmatchedDays)
+	}
+}
+
+func TestMessagePairsPauses(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// a solo-only pause doesn't take Grace out of pairing, a pairing one does
+	grace, _, _ := s.GetRecurser(ctx, "2")
+	grace.Pauses = []Pause{
+		{Start: now.Format(dateLayout), End: now.AddDate(0, 0, 1).Format(dateLayout)},
+		{Start: now.AddDate(0, 0, 1).Format(dateLayout), End: now.AddDate(0, 0, 1).Format(dateLayout), Pairing: true},
+	}
+	s.PutRecurser(ctx, grace)
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Pauses = []Pause{{Start: now.Format(dateLayout), End: now.Format(dateLayout), Pairing: true}}
+	s.PutRecurser(ctx, ada)
+
+	// Ada sits today out and Grace is told she wasn't matched
+	MessagePairs(s, zulip.client(), now, ctx)
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 0 {
+		t.Errorf("Expected paused Ada to get nothing, got %v", got)
+	}
+	if got := zulip.privateMessages("grace@example.com"); !reflect.DeepEqual(got, []string{botMessages.NotMatched}) {
+		t.Errorf("Expected Grace to go unmatched, got %v", got)
+	}
+	if ada, _, _ := s.GetRecurser(ctx, "1"); !ada.IsPairingTomorrow || ada.UnmatchedDays != 0 {
+		t.Errorf("Expected Ada to stay queued without counting the day, got %v", ada)
+	}
+
+	// tomorrow it's Grace who's away
+	MessagePairs(s, zulip.client(), now.AddDate(0, 0, 1), ctx)
+	if got := zulip.privateMessages("ada@example.com"); !reflect.DeepEqual(got, []string{botMessages.NotMatched}) {
+		t.Errorf("Expected Ada to go unmatched, got %v", got)
+	}
+
+	MessagePairs(s, zulip.client(), now.AddDate(0, 0, 2), ctx)
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); !reflect.DeepEqual(got, []string{botMessages.Matched}) {
+		t.Errorf("Expected Ada and Grace to be matched once both are back, got %v", got)
+	}
+}
+
+func TestPostDaily(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	PostDaily(s, zulip.client(), time.Now(), ctx)
+
+	got := zulip.streamMessages("Daily LeetCode", "AlgoBot Daily Question")
+	if len(got) != 1 || !strings.HasPrefix(got[0], "**AlgoBot Daily Question (") {
+		t.Errorf("Expected the daily question to be posted, got %v", got)
+	}
+
+	now := time.Now()
+	today := fmt.Sprintf("%v-%v-%v", now.Month(), now.Day(), now.Year())
+	if _, ok := s.DailyQuestion(today); !ok {
+		t.Errorf("Expected the daily question to be recorded for %s", today)
+	}
+}
+
+func TestPostDailyRetry(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC)
+
+	// the first post is turned away, so the retry gets to make it
+	zulip.reject("Daily LeetCode")
+	if err := runJob(findJob("daily"), s, zulip.client(), now, ctx); err == nil {
+		t.Errorf("Expected the daily job to fail when its post is turned away")
+	}
+	if _, ok := s.DailyQuestion("October-19-2026"); ok {
+		t.Errorf("Expected no daily question to be recorded before it's posted")
+	}
+
+	if got := runJobNow("daily", s, zulip.client(), now, ctx); got != "The daily job ran!" {
+		t.Errorf("Expected the retry to run, got %q", got)
+	}
+	if got := zulip.streamMessages("Daily LeetCode", "AlgoBot Daily Question"); len(got) != 1 {
+		t.Errorf("Expected the daily question to be posted once, got %v", got)
+	}
+	if _, ok := s.DailyQuestion("October-19-2026"); !ok {
+		t.Errorf("Expected the daily question to be recorded once it's posted")
+	}
+}
+
+func TestJobScheduleSlots(t *testing.T) {
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	table := []struct {
+		schedule jobSchedule
+		after    time.Time
+		until    time.Time
+		want     []time.Time
+	}{
+		{hourly, at(19, 8, 0), at(19, 8, 59), nil},
+		{hourly, at(19, 8, 0), at(19, 9, 0), []time.Time{at(19, 9, 0)}},
+		{hourly, at(19, 8, 30), at(19, 11, 5), []time.Time{at(19, 9, 0), at(19, 10, 0), at(19, 11, 0)}},
+		{dailyAt(9), at(19, 8, 0), at(19, 9, 10), []time.Time{at(19, 9, 0)}},
+		{dailyAt(9), at(19, 9, 0), at(20, 8, 0), nil},
+		{dailyAt(9), at(18, 9, 0), at(20, 9, 0), []time.Time{at(19, 9, 0), at(20, 9, 0)}},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := test.schedule.slots(test.after, test.until)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestRunDueJobs(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	var ran []string
+	failing := true
+	defer func(registered []*job) { jobs = registered }(jobs)
+	jobs = []*job{
+		{
+			name:     "hourly",
+			schedule: hourly,
+			run: func(store Store, zulip ZulipSender, at time.Time, ctx context.Context) {
+				ran = append(ran, at.Format("hourly 15:04"))
+			},
+		},
+		{
+			name:     "daily",
+			schedule: dailyAt(9),
+			run: func(store Store, zulip ZulipSender, at time.Time, ctx context.Context) {
+				if failing {
+					panic("no API key")
+				}
+				ran = append(ran, at.Format("daily 15:04"))
+			},
+		},
+	}
+
+	// a day's worth of hours from 11:00 on the 20th, then the daily job
+	var catchUp []string
+	for hour := 11; hour < 35; hour++ {
+		catchUp = append(catchUp, fmt.Sprintf("hourly %02d:00", hour%24))
+	}
+	catchUp = append(catchUp, "daily 09:00")
+
+	steps := []struct {
+		now  time.Time
+		want []string
+	}{
+		// jobs that never ran start with the latest hour
+		{time.Date(2026, 10, 19, 9, 5, 0, 0, time.UTC), []string{"hourly 09:00"}},
+		{time.Date(2026, 10, 19, 9, 15, 0, 0, time.UTC), nil},
+		// missed runs are caught up on, and failed ones retried
+		{time.Date(2026, 10, 19, 12, 5, 0, 0, time.UTC), []string{"hourly 10:00", "hourly 11:00", "hourly 12:00", "daily 09:00"}},
+		// but no further back than a day
+		{time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC), catchUp},
+	}
+
+	for i, step := range steps {
+		if i == 2 {
+			failing = false
+		}
+		ran = nil
+		runDueJobs(s, zulip.client(), step.now, ctx)
+		if !reflect.DeepEqual(ran, step.want) {
+			t.Errorf("Step %v: Expected %v, got %v", i, step.want, ran)
+		}
+	}
+}
+
+// microsecondJobRuns keeps job runs to the microsecond, as Firestore does
+type microsecondJobRuns struct {
+	Store
+}
+
+func (s microsecondJobRuns) RecordJobRun(ctx context.Context, job string, last time.Time, at time.Time) (bool, error) {
+	return s.Store.RecordJobRun(ctx, job, last, at.Truncate(time.Microsecond))
+}
+
+func TestRunDueJobsFirstRun(t *testing.T) {
+	s := microsecondJobRuns{newTestStore(t)}
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	var ran []string
+	defer func(registered []*job) { jobs = registered }(jobs)
+	jobs = []*job{
+		{
+			name:     "hourly",
+			schedule: hourly,
+			run: func(store Store, zulip ZulipSender, at time.Time, ctx context.Context) {
+				ran = append(ran, at.Format("hourly 15:04"))
+			},
+		},
+	}
+
+	runDueJobs(s, zulip.client(), time.Date(2026, 10, 19, 9, 5, 0, 123456789, time.UTC), ctx)
+	if want := []string{"hourly 09:00"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Expected %v, got %v", want, ran)
+	}
+}
+
+func TestRunDueJobsOverlapping(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 5, 0, 0, time.UTC)
+	s.RecordJobRun(ctx, "hourly", time.Time{}, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+
+	// a tick that starts while the first one is still running the 11:00
+	// run leaves it alone
+	var ran []string
+	defer func(registered []*job) { jobs = registered }(jobs)
+	jobs = []*job{
+		{
+			name:     "hourly",
+			schedule: hourly,
+			run: func(store Store, zulip ZulipSender, at time.Time, ctx context.Context) {
+				ran = append(ran, at.Format("hourly 15:04"))
+				if len(ran) == 1 {
+					runDueJobs(store, zulip, now, ctx)
+				}
+			},
+		},
+	}
+
+	runDueJobs(s, zulip.client(), now, ctx)
+	if want := []string{"hourly 11:00", "hourly 12:00"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Expected %v, got %v", want, ran)
+	}
+	if got, _ := s.LastJobRun(ctx, "hourly"); !got.Equal(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the last run to be 12:00, got %v", got)
+	}
+}
+
+func TestJobsCommand(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	defer SetAdmins(admins)
+	SetAdmins([]string{"2"})
+
+	for _, cmd := range []string{"jobs", "jobs run", "jobs run nothing"} {
+		if got, _ := handleCommand(ctx, cmd, "1", "ada@example.com", "Ada Lovelace"); got != "Sorry, only AlgoBot's admins can do that!" {
+			t.Errorf("Expected Ada to be turned away from %q, got %q", cmd, got)
+		}
+	}
+	if help := helpText(); strings.Contains(help, "jobs") {
+		t.Errorf("Expected admin commands to be left out of help, got %q", help)
+	}
+
+	s.RecordJobRun(ctx, "pairs", time.Time{}, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC))
+	want := "**Scheduled jobs:**\n" +
+		"* `pairs` matches everyone in the pairing queue and messages them their partners, every day at 09:00 UTC (last ran: Mon Oct 19 09:00 UTC)\n" +
+		"* `solo` sends a solo question to everyone whose hour it is, every hour (last ran: never)\n" +
+		"* `daily` posts the daily question to #**Daily LeetCode**, every day at 13:00 UTC (last ran: never)\n\n" +
+		"Use `jobs run <job>` to run one now."
+	if got, _ := handleCommand(ctx, "jobs", "2", "grace@example.com", "Grace Hopper"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// a manual run counts as the latest scheduled one
+	now := time.Date(2026, 10, 19, 14, 30, 0, 0, time.UTC)
+	if got := runJobNow("daily", s, zulip.client(), now, ctx); got != "The daily job ran!" {
+		t.Errorf("Expected the daily job to run, got %q", got)
+	}
+	if got, _ := s.LastJobRun(ctx, "daily"); !got.Equal(time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the run to be recorded for 13:00, got %v", got)
+	}
+	if got := zulip.streamMessages("Daily LeetCode", "AlgoBot Daily Question"); len(got) != 1 {
+		t.Errorf("Expected the daily question to be posted, got %v", got)
+	}
+}
+
+func TestCronJobsAreIdempotent(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+
+	now := soloHourToday()
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Config.SoloDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	s.PutRecurser(ctx, ada)
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Config.SoloDays = ada.Config.SoloDays
+	alan.SkipDates = []string{now.Format(dateLayout)}
+	s.PutRecurser(ctx, alan)
+
+	// App Engine retries each of them
+	for i := 0; i < 2; i++ {
+		MessageSolo(s, zulip.client(), now, ctx)
+		MessagePairs(s, zulip.client(), now, ctx)
+		PostDaily(s, zulip.client(), now, ctx)
+	}
+
+	// Ada gets her solo question and her partner's, Alan skipped his
+	if got := zulip.privateMessages("ada@example.com"); len(got) != 2 {
+		t.Errorf("Expected Ada to get two messages, got %v", got)
+	}
+	if got, _ := s.SoloSessions(ctx, "1"); len(got) != 1 {
+		t.Errorf("Expected one solo session for Ada, got %v", got)
+	}
+	if got := zulip.privateMessages("alan@example.com"); len(got) != 0 {
+		t.Errorf("Expected Alan's skip to hold on the retry, got %v", got)
+	}
+	if got := zulip.privateMessages("ada@example.com", "grace@example.com"); len(got) != 1 {
+		t.Errorf("Expected Ada and Grace to be matched once, got %v", got)
+	}
+	if got, _ := s.PairingSessions(ctx, "2"); len(got) != 1 {
+		t.Errorf("Expected one pairing session for Grace, got %v", got)
+	}
+	if got := zulip.streamMessages("Daily LeetCode", "AlgoBot Daily Question"); len(got) != 1 {
+		t.Errorf("Expected the daily question to be posted once, got %v", got)
+	}
+
+	// a new hour for Ada's question doesn't mean a second one today
+	ada, _, _ = s.GetRecurser(ctx, "1")
+	ada.Config.SoloTime = "12:00"
+	s.PutRecurser(ctx, ada)
+	MessageSolo(s, zulip.client(), now.Add(time.Hour), ctx)
+	if got, _ := s.SoloSessions(ctx, "1"); len(got) != 1 {
+		t.Errorf("Expected Ada to get one question a day, got %v", got)
+	}
+}
@@ -5,75 +5,65 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
-	"os/exec"
 	"strings"
 	"time"
-
-	"cloud.google.com/go/firestore"
-	"google.golang.org/api/iterator"
 )
 
-func PostDaily(client *firestore.Client, ctx context.Context) {
-	t := time.Now()
-	today := fmt.Sprintf("%v-%v-%v", t.Month(), t.Day(), t.Year())
-
-	question := generateDailyQuestion(today, ctx)
+// PostDaily posts the daily question. The run ledger keeps it to one post a
+// day however many times it runs.
+func PostDaily(store Store, zulip ZulipSender, now time.Time, ctx context.Context) {
+	today := fmt.Sprintf("%v-%v-%v", now.Month(), now.Day(), now.Year())
+	run := runKey("daily", now.Format(dateLayout))
+	stream := "Daily LeetCode"
+	topic := "AlgoBot Daily Question"
 
-	session := map[string]interface{}{
-		"question":  question["id"],
-		"timeStamp": time.Now(),
+	if !claimDeliveries(store, run, []string{stream}, now, ctx) {
+		log.Println("The daily question already went out today")
+		return
 	}
 
-	_, err := client.Collection("dailyQuestions").Doc(today).Create(ctx, session)
-
-	if err != nil {
-		log.Println(err)
-	} else {
-		log.Println("A daily question was recorded")
-	}
-
-	doc, err := client.Collection("auth").Doc("api").Get(ctx)
-	if err != nil {
-		log.Panic(err)
+	question := generateDailyQuestion(store, now, ctx)
+	if question == nil {
+		log.Println("No question could be found for the daily question")
+		releaseDeliveries(store, run, []string{stream}, ctx)
+		return
 	}
 
-	apikey := doc.Data()["key"]
-
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("**AlgoBot Daily Question (%s):**\n\n", today))
-	builder.WriteString(fmt.Sprintf("[%v. %s](%s) [%s]\n\n", question["id"], question["name"], question["url"], strings.Title(question["difficulty"].(string))))
+	builder.WriteString(fmt.Sprintf("[%v. %s](%s) [%s]\n\n", question.Id, question.Name, question.URL, strings.Title(question.Difficulty)))
 	builder.WriteString("Feel free to post your answers below (but take care to add spoilers!).\n")
 	builder.WriteString(fmt.Sprintf("Problems get more difficult as the week progresses. Check out [the schedule](%s#daily-questions)!\n\n", githubURL))
 
 	builder.WriteString("Send me a DM to create a study schedule and practice mock interviews.")
 
 	msg := builder.String()
-	stream := "Daily LeetCode"
-	topic := "AlgoBot Daily Question"
 
-	// Directly run curl as noted in the Zulip API docs
-	cmd := exec.Command(
-		"curl", "-X", "POST", zulipAPIURL,
-		"-u", fmt.Sprintf("%s:%s", botEmailAddress, apikey),
-		"--data-urlencode", "type=stream",
-		"--data-urlencode", fmt.Sprintf("to=%s", stream),
-		"--data-urlencode", fmt.Sprintf("subject=%s", topic),
-		"--data-urlencode", fmt.Sprintf("content=%s", msg),
-	)
-	fmt.Println(cmd)
+	// a failed post gives the day back to a retry, so the question is only
+	// recorded once it's out
+	_, err := zulip.SendStream(stream, topic, msg)
+	if err != nil {
+		releaseDeliveries(store, run, []string{stream}, ctx)
+		log.Panic(err)
+	}
+	log.Println("A daily question was sent out")
 
-	err = cmd.Run()
+	daily := DailyQuestion{
+		Question:  question.Id,
+		TimeStamp: now,
+	}
 
+	err = store.CreateDailyQuestion(ctx, today, daily)
 	if err != nil {
 		log.Println(err)
 	} else {
-		log.Println("A daily question was sent out")
+		log.Println("A daily question was recorded")
 	}
 }
 
-func generateDailyQuestion(today string, ctx context.Context) map[string]interface{} {
+func generateDailyQuestion(store Store, now time.Time, ctx context.Context) *Question {
 	var difficulty string
-	switch day := strings.ToLower(time.Now().Weekday().String())[:3]; day {
+	switch day := weekday(now); day {
 	case "mon":
 		difficulty = "easy"
 	case "tue":
@@ -90,24 +80,20 @@ func generateDailyQuestion(today string, ctx context.Context) map[string]interfa
 		difficulty = "hard"
 	}
 
-	iter := client.Collection("questions").Where("difficulty", "==", difficulty).Documents(ctx)
-	var documents []*firestore.DocumentSnapshot
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Panic(err)
-		}
-		documents = append(documents, doc)
+	questions, err := store.Questions(ctx, QuestionQuery{Difficulty: difficulty})
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	if len(questions) == 0 {
+		return nil
 	}
 
 	s := rand.NewSource(time.Now().UnixNano())
 	r := rand.New(s)
 
-	selection := documents[r.Intn(len(documents))]
-	question := selection.Data()
+	selection := questions[r.Intn(len(questions))]
 
-	return question
+	return &selection
 }
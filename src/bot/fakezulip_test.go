@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeZulip stands in for recurse.zulipchat.com. It implements the send-message
+// endpoint (recording everything the bot says) and can play a user sending the
+// bot a private message through the outgoing webhook.
+type fakeZulip struct {
+	t        *testing.T
+	server   *httptest.Server
+	apiKey   string
+	botToken string
+
+	mu       sync.Mutex
+	messages []fakeMessage
+	// rejects is how many more messages to each set of recipients the fake
+	// turns away, keyed like fakeMessage.To joined with commas
+	rejects map[string]int
+}
+
+// fakeMessage is one message sent through the fake, either by the bot
+// through the API or by a user through sendToBot
+type fakeMessage struct {
+	Type    string // "private" or "stream"
+	From    string
+	To      []string // emails for private messages, the stream for stream messages
+	Topic   string
+	Content string
+}
+
+func newFakeZulip(t *testing.T) *fakeZulip {
+	t.Helper()
+
+	z := &fakeZulip{t: t, apiKey: "test-api-key", botToken: "test-bot-token"}
+	z.server = httptest.NewServer(http.HandlerFunc(z.handleMessages))
+	t.Cleanup(z.server.Close)
+	return z
+}
+
+// client is a ZulipClient for the bot pointed at the fake
+func (z *fakeZulip) client() *ZulipClient {
+	return NewZulipClient(z.server.URL, botEmailAddress, z.apiKey)
+}
+
+func (z *fakeZulip) handleMessages(w http.ResponseWriter, r *http.Request) {
+	reply := func(status int, body string) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+
+	if r.Method != "POST" || r.URL.Path != "/api/v1/messages" {
+		reply(http.StatusNotFound, `{"result": "error", "msg": "Not found", "code": "BAD_REQUEST"}`)
+		return
+	}
+	email, key, ok := r.BasicAuth()
+	if !ok || email != botEmailAddress || key != z.apiKey {
+		reply(http.StatusUnauthorized, `{"result": "error", "msg": "Invalid API key", "code": "UNAUTHORIZED"}`)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		reply(http.StatusBadRequest, `{"result": "error", "msg": "Malformed form", "code": "BAD_REQUEST"}`)
+		return
+	}
+
+	message := fakeMessage{
+		Type:    r.PostForm.Get("type"),
+		From:    botEmailAddress,
+		Topic:   r.PostForm.Get("topic"),
+		Content: r.PostForm.Get("content"),
+	}
+	switch message.Type {
+	case "private":
+		// Zulip accepts either a JSON list or a comma separated string
+		to := r.PostForm.Get("to")
+		if err := json.Unmarshal([]byte(to), &message.To); err != nil {
+			for _, email := range strings.Split(to, ",") {
+				message.To = append(message.To, strings.TrimSpace(email))
+			}
+		}
+		sort.Strings(message.To)
+	case "stream":
+		message.To = []string{r.PostForm.Get("to")}
+		if message.Topic == "" {
+			message.Topic = r.PostForm.Get("subject")
+		}
+	default:
+		reply(http.StatusBadRequest, `{"result": "error", "msg": "Invalid message type", "code": "BAD_REQUEST"}`)
+		return
+	}
+
+	z.mu.Lock()
+	if to := strings.Join(message.To, ","); z.rejects[to] > 0 {
+		z.rejects[to]--
+		z.mu.Unlock()
+		reply(http.StatusTooManyRequests, `{"result": "error", "msg": "API usage exceeded rate limit", "code": "RATE_LIMIT_HIT"}`)
+		return
+	}
+	z.messages = append(z.messages, message)
+	id := len(z.messages)
+	z.mu.Unlock()
+
+	reply(http.StatusOK, fmt.Sprintf(`{"result": "success", "msg": "", "id": %d}`, id))
+}
+
+// reject turns away the next message sent to exactly these recipients, or
+// this stream, the way Zulip does when the bot hits its rate limit
+func (z *fakeZulip) reject(to ...string) {
+	to = append([]string{}, to...)
+	sort.Strings(to)
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.rejects == nil {
+		z.rejects = make(map[string]int)
+	}
+	z.rejects[strings.Join(to, ",")]++
+}
+
+// sendToBot delivers a private message from the given user to Webhook, the way
+// Zulip's outgoing webhook would, and returns the bot's reply
+func (z *fakeZulip) sendToBot(sender Recurser, content string) string {
+	z.t.Helper()
+
+	var payload incomingJSON
+	payload.Data = content
+	payload.Token = z.botToken
+	payload.Trigger = "private_message"
+	payload.Message.SenderID = atoi(z.t, sender.Id)
+	payload.Message.SenderEmail = sender.Email
+	payload.Message.SenderFullName = sender.Name
+	payload.Message.DisplayRecipient = []interface{}{
+		map[string]interface{}{"email": sender.Email},
+		map[string]interface{}{"email": botEmailAddress},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		z.t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	Webhook(w, httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body)))
+
+	var response botResponse
+	if err = json.NewDecoder(w.Body).Decode(&response); err != nil {
+		z.t.Fatalf("Webhook did not reply with JSON: %v", err)
+	}
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.messages = append(z.messages,
+		fakeMessage{Type: "private", From: sender.Email, To: []string{botEmailAddress}, Content: content},
+		fakeMessage{Type: "private", From: botEmailAddress, To: []string{sender.Email}, Content: response.Message},
+	)
+	return response.Message
+}
+
+// privateMessages returns the content of every private message sent to
+// exactly this set of recipients, in order
+func (z *fakeZulip) privateMessages(to ...string) []string {
+	to = append([]string{}, to...)
+	sort.Strings(to)
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var contents []string
+	for _, message := range z.messages {
+		if message.Type == "private" && strings.Join(message.To, ",") == strings.Join(to, ",") {
+			contents = append(contents, message.Content)
+		}
+	}
+	return contents
+}
+
+// streamMessages returns the content of every message posted to a stream topic, in order
+func (z *fakeZulip) streamMessages(stream string, topic string) []string {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var contents []string
+	for _, message := range z.messages {
+		if message.Type == "stream" && message.To[0] == stream && message.Topic == topic {
+			contents = append(contents, message.Content)
+		}
+	}
+	return contents
+}
+
+// conversation returns both sides of a user's private chat with the bot
+func (z *fakeZulip) conversation(email string) []fakeMessage {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	var messages []fakeMessage
+	for _, message := range z.messages {
+		if message.Type != "private" {
+			continue
+		}
+		fromUser := message.From == email && len(message.To) == 1 && message.To[0] == botEmailAddress
+		toUser := message.From == botEmailAddress && len(message.To) == 1 && message.To[0] == email
+		if fromUser || toUser {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func atoi(t *testing.T, s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatalf("%q is not a Zulip user id", s)
+	}
+	return i
+}
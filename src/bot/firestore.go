@@ -0,0 +1,398 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/fatih/structs"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// firestoreStore is the original Google Cloud Firestore backend.
+// Collections are laid out as follows:
+//
+//	recursers/{userID}        - Recurser
+//	soloSessions/{userID}     - {"sessions": []SoloSession}
+//	pairingSessions/{userID}  - {"sessions": []PairingSession}
+//	questions/{questionID}    - Question (populated by scripts/main.py)
+//	problemSets/{pset}        - ProblemSet (populated by scripts/main.py)
+//	dailyQuestions/{date}     - DailyQuestion
+//	jobRuns/{job}             - JobRun
+//	deliveries/{run}:{userID} - Delivery
+//	auth/bot, auth/api        - Zulip secrets
+//	auth/link                 - the key page links are signed with
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestoreStore connects to the Firestore database of the given GCP project
+func NewFirestoreStore(ctx context.Context, projectID string) (Store, error) {
+	client, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &firestoreStore{client}, nil
+}
+
+func (s *firestoreStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *firestoreStore) GetRecurser(ctx context.Context, id string) (Recurser, bool, error) {
+	var recurser Recurser
+
+	doc, err := s.client.Collection("recursers").Doc(id).Get(ctx)
+	if err != nil {
+		// a missing document just means they never subscribed
+		if grpc.Code(err) == codes.NotFound {
+			return recurser, false, nil
+		}
+		return recurser, false, err
+	}
+
+	if err = doc.DataTo(&recurser); err != nil {
+		return recurser, true, err
+	}
+	return recurser, true, nil
+}
+
+func (s *firestoreStore) PutRecurser(ctx context.Context, recurser Recurser) error {
+	_, err := s.client.Collection("recursers").Doc(recurser.Id).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	return err
+}
+
+func (s *firestoreStore) DeleteRecurser(ctx context.Context, id string) error {
+	_, err := s.client.Collection("recursers").Doc(id).Delete(ctx)
+	return err
+}
+
+func (s *firestoreStore) UpdateConfig(ctx context.Context, id string, config UserConfig) error {
+	doc := s.client.Collection("recursers").Doc(id)
+	_, err := doc.Update(ctx, []firestore.Update{{Path: "config", Value: structs.Map(config)}})
+	return err
+}
+
+func (s *firestoreStore) UpdateRecurser(ctx context.Context, id string, update RecurserUpdate) error {
+	var updates []firestore.Update
+	if update.IsSkippingTomorrow != nil {
+		updates = append(updates, firestore.Update{Path: "isSkippingTomorrow", Value: *update.IsSkippingTomorrow})
+	}
+	if update.IsPairingTomorrow != nil {
+		updates = append(updates, firestore.Update{Path: "isPairingTomorrow", Value: *update.IsPairingTomorrow})
+	}
+	if update.QueuedAt != nil {
+		updates = append(updates, firestore.Update{Path: "queuedAt", Value: *update.QueuedAt})
+	}
+	if update.UnmatchedDays != nil {
+		updates = append(updates, firestore.Update{Path: "unmatchedDays", Value: *update.UnmatchedDays})
+	}
+	if update.RematchWith != nil {
+		updates = append(updates, firestore.Update{Path: "rematchWith", Value: *update.RematchWith})
+	}
+	if update.SkipDates != nil {
+		updates = append(updates, firestore.Update{Path: "skipDates", Value: *update.SkipDates})
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	_, err := s.client.Collection("recursers").Doc(id).Update(ctx, updates)
+	return err
+}
+
+func (s *firestoreStore) PairingQueue(ctx context.Context) ([]Recurser, error) {
+	iter := s.client.Collection("recursers").Where("isPairingTomorrow", "==", true).Documents(ctx)
+	return iterToRecurserList(iter)
+}
+
+func (s *firestoreStore) SoloRecipients(ctx context.Context, day string) ([]Recurser, error) {
+	iter := s.client.Collection("recursers").
+		Where("isSkippingTomorrow", "==", false).
+		Where("config.soloDays", "array-contains", day).
+		Documents(ctx)
+	return iterToRecurserList(iter)
+}
+
+func (s *firestoreStore) Skippers(ctx context.Context) ([]Recurser, error) {
+	iter := s.client.Collection("recursers").Where("isSkippingTomorrow", "==", true).Documents(ctx)
+	return iterToRecurserList(iter)
+}
+
+func (s *firestoreStore) CreateSessionHistory(ctx context.Context, id string) error {
+	sessions := map[string]interface{}{
+		"sessions": []interface{}{},
+	}
+
+	_, err := s.client.Collection("soloSessions").Doc(id).Create(ctx, sessions)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Collection("pairingSessions").Doc(id).Create(ctx, sessions)
+	return err
+}
+
+func (s *firestoreStore) DeleteSessionHistory(ctx context.Context, id string) error {
+	_, err := s.client.Collection("soloSessions").Doc(id).Delete(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Collection("pairingSessions").Doc(id).Delete(ctx)
+	return err
+}
+
+func (s *firestoreStore) AppendSoloSession(ctx context.Context, id string, session SoloSession) error {
+	doc := s.client.Collection("soloSessions").Doc(id)
+	_, err := doc.Update(ctx, []firestore.Update{{Path: "sessions", Value: firestore.ArrayUnion(session)}})
+	return err
+}
+
+func (s *firestoreStore) UpdateLastSoloSession(ctx context.Context, id string, session SoloSession) error {
+	doc := s.client.Collection("soloSessions").Doc(id)
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snapshot, err := tx.Get(doc)
+		if err != nil {
+			return err
+		}
+		var history struct {
+			Sessions []SoloSession `firestore:"sessions"`
+		}
+		if err = snapshot.DataTo(&history); err != nil {
+			return err
+		}
+		if len(history.Sessions) == 0 {
+			return fmt.Errorf("soloSessions/%s: no sessions to update", id)
+		}
+		history.Sessions[len(history.Sessions)-1] = session
+		return tx.Update(doc, []firestore.Update{{Path: "sessions", Value: history.Sessions}})
+	})
+}
+
+func (s *firestoreStore) AppendPairingSession(ctx context.Context, id string, session PairingSession) error {
+	doc := s.client.Collection("pairingSessions").Doc(id)
+	_, err := doc.Update(ctx, []firestore.Update{{Path: "sessions", Value: firestore.ArrayUnion(session)}})
+	return err
+}
+
+func (s *firestoreStore) SoloSessions(ctx context.Context, id string) ([]SoloSession, error) {
+	var history struct {
+		Sessions []SoloSession `firestore:"sessions"`
+	}
+	err := s.getSessionHistory(ctx, "soloSessions", id, &history)
+	return history.Sessions, err
+}
+
+func (s *firestoreStore) PairingSessions(ctx context.Context, id string) ([]PairingSession, error) {
+	var history struct {
+		Sessions []PairingSession `firestore:"sessions"`
+	}
+	err := s.getSessionHistory(ctx, "pairingSessions", id, &history)
+	return history.Sessions, err
+}
+
+// getSessionHistory reads a session document into history; users without
+// one simply have no sessions yet
+func (s *firestoreStore) getSessionHistory(ctx context.Context, collection string, id string, history interface{}) error {
+	doc, err := s.client.Collection(collection).Doc(id).Get(ctx)
+	if grpc.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return doc.DataTo(history)
+}
+
+func (s *firestoreStore) Questions(ctx context.Context, query QuestionQuery) ([]Question, error) {
+	q := s.client.Collection("questions").Query
+	if query.Id != 0 {
+		q = q.Where("id", "==", query.Id)
+	}
+	if query.Difficulty != "" {
+		q = q.Where("difficulty", "==", query.Difficulty)
+	}
+
+	// Firestore only allows a single array-contains per query, so the pset is
+	// filtered client-side whenever a topic is also requested
+	filterPset := query.ProblemSet != "" && query.ProblemSet != "random"
+	switch {
+	case query.Tag != "":
+		q = q.Where("tags", "array-contains", query.Tag)
+	case filterPset:
+		q = q.Where("psets", "array-contains", query.ProblemSet)
+		filterPset = false
+	}
+
+	var questions []Question
+	iter := q.Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var question Question
+		if err = doc.DataTo(&question); err != nil {
+			return nil, err
+		}
+		if filterPset && !contains(question.Psets, query.ProblemSet) {
+			continue
+		}
+		questions = append(questions, question)
+	}
+
+	return questions, nil
+}
+
+func (s *firestoreStore) ProblemSet(ctx context.Context, name string) ([]int, error) {
+	doc, err := s.client.Collection("problemSets").Doc(name).Get(ctx)
+	if err != nil {
+		if grpc.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var pset ProblemSet
+	err = doc.DataTo(&pset)
+	return pset.Questions, err
+}
+
+func (s *firestoreStore) CreateDailyQuestion(ctx context.Context, date string, daily DailyQuestion) error {
+	_, err := s.client.Collection("dailyQuestions").Doc(date).Create(ctx, daily)
+	return err
+}
+
+func (s *firestoreStore) LastJobRun(ctx context.Context, job string) (time.Time, error) {
+	var run JobRun
+
+	doc, err := s.client.Collection("jobRuns").Doc(job).Get(ctx)
+	if err != nil {
+		if grpc.Code(err) == codes.NotFound {
+			return run.LastRun, nil
+		}
+		return run.LastRun, err
+	}
+
+	err = doc.DataTo(&run)
+	return run.LastRun, err
+}
+
+func (s *firestoreStore) RecordJobRun(ctx context.Context, job string, last time.Time, at time.Time) (bool, error) {
+	doc := s.client.Collection("jobRuns").Doc(job)
+	recorded := false
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		recorded = false
+		var run JobRun
+		snapshot, err := tx.Get(doc)
+		if err != nil && grpc.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err = snapshot.DataTo(&run); err != nil {
+				return err
+			}
+		}
+		if !run.LastRun.Equal(last) {
+			return nil
+		}
+		recorded = true
+		return tx.Set(doc, JobRun{LastRun: at})
+	})
+	return recorded, err
+}
+
+func (s *firestoreStore) ClaimDelivery(ctx context.Context, delivery Delivery) (bool, error) {
+	_, err := s.client.Collection("deliveries").Doc(deliveryID(delivery.Run, delivery.Recipient)).Create(ctx, delivery)
+	if grpc.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *firestoreStore) ReleaseDelivery(ctx context.Context, run string, recipient string) error {
+	_, err := s.client.Collection("deliveries").Doc(deliveryID(run, recipient)).Delete(ctx)
+	return err
+}
+
+func (s *firestoreStore) Deliveries(ctx context.Context, run string) ([]Delivery, error) {
+	var deliveries []Delivery
+	iter := s.client.Collection("deliveries").Where("run", "==", run).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var delivery Delivery
+		if err = doc.DataTo(&delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// deliveryID is the document ID of a recipient's delivery, which makes
+// claiming it twice fail
+func deliveryID(run string, recipient string) string {
+	return run + ":" + recipient
+}
+
+func (s *firestoreStore) BotToken(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "bot", "token")
+}
+
+func (s *firestoreStore) APIKey(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "api", "key")
+}
+
+func (s *firestoreStore) LinkSecret(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "link", "secret")
+}
+
+// secrets are manually put into the auth collection before deployment
+func (s *firestoreStore) readSecret(ctx context.Context, doc string, field string) (string, error) {
+	snap, err := s.client.Collection("auth").Doc(doc).Get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := snap.DataAt(field)
+	if err != nil {
+		return "", err
+	}
+	secret, _ := value.(string)
+	return secret, nil
+}
+
+func iterToRecurserList(iter *firestore.DocumentIterator) ([]Recurser, error) {
+	var recursersList []Recurser
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var recurser Recurser
+		if err = doc.DataTo(&recurser); err != nil {
+			return nil, err
+		}
+		recursersList = append(recursersList, recurser)
+	}
+
+	return recursersList, nil
+}
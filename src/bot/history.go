@@ -0,0 +1,299 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// historyFilters narrow down the history page. Empty filters match anything.
+type historyFilters struct {
+	From       string // first day shown (YYYY-MM-DD)
+	To         string // last day shown (YYYY-MM-DD)
+	Topic      string
+	Difficulty string
+}
+
+// matches reports whether a session on the date with the question passes the
+// filters. Sessions without a known question only pass without a topic or
+// difficulty.
+func (f historyFilters) matches(date string, question *Question) bool {
+	if (f.From != "" && date < f.From) || (f.To != "" && date > f.To) {
+		return false
+	}
+	if f.Topic != "" && (question == nil || !contains(question.Tags, f.Topic)) {
+		return false
+	}
+	if f.Difficulty != "" && (question == nil || question.Difficulty != f.Difficulty) {
+		return false
+	}
+	return true
+}
+
+// soloEntry is a row of the history page's solo sessions
+type soloEntry struct {
+	Date     string
+	Question *Question
+	Review   bool
+	Outcome  string
+}
+
+// pairingEntry is a row of the history page's pairing sessions. Role is
+// what the recurser was in the session.
+type pairingEntry struct {
+	Date      string
+	Partner   string
+	Role      string
+	Question  *Question
+	timeStamp time.Time
+}
+
+// historyPage is what history.html is filled in with
+type historyPage struct {
+	Name              string
+	Token             string
+	Filters           historyFilters
+	TopicOptions      []string
+	DifficultyOptions []string
+	Solo              []soloEntry
+	SoloTotal         int
+	Pairing           []pairingEntry
+	PairingTotal      int
+	// Errors maps filters to what's wrong with them
+	Errors map[string]string
+}
+
+// History serves the page behind the link the history command hands out,
+// which must carry a valid token for the ID in the path
+func History(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if r.Method != "GET" {
+		fmt.Fprintf(w, "Sorry, only the GET method is supported.")
+		return
+	}
+	if !authorizeLink(w, r, "history", id) {
+		return
+	}
+
+	ctx := r.Context()
+	recurser, isSubscribed, err := store.GetRecurser(ctx, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Sorry, your history couldn't be loaded. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	if !isSubscribed {
+		http.Error(w, "You aren't subscribed to AlgoBot, so there's no history to show. Send subscribe to AlgoBot on Zulip first.", http.StatusNotFound)
+		return
+	}
+
+	filters, errs := parseHistoryFilters(r.URL.Query())
+	page, err := loadHistory(recurser, filters, store, ctx)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "Sorry, your history couldn't be loaded. Please try again.", http.StatusInternalServerError)
+		return
+	}
+	page.Token = r.FormValue("token")
+	page.Errors = errs
+	renderTemplate(w, http.StatusOK, "history.html", page)
+}
+
+// parseHistoryFilters reads the filters from the page's query, dropping any
+// that don't make sense along with a note of what's wrong with them
+func parseHistoryFilters(query url.Values) (historyFilters, map[string]string) {
+	var errs map[string]string
+	fail := func(field string, reason string) {
+		if errs == nil {
+			errs = make(map[string]string)
+		}
+		errs[field] = reason
+	}
+
+	filters := historyFilters{
+		From:       query.Get("from"),
+		To:         query.Get("to"),
+		Topic:      query.Get("topic"),
+		Difficulty: query.Get("difficulty"),
+	}
+	if _, err := time.Parse(dateLayout, filters.From); filters.From != "" && err != nil {
+		fail("from", fmt.Sprintf("%q isn't a date like 2026-11-02.", filters.From))
+		filters.From = ""
+	}
+	if _, err := time.Parse(dateLayout, filters.To); filters.To != "" && err != nil {
+		fail("to", fmt.Sprintf("%q isn't a date like 2026-11-02.", filters.To))
+		filters.To = ""
+	}
+	if filters.From != "" && filters.To != "" && filters.To < filters.From {
+		fail("to", "The last day can't be before the first.")
+		filters.To = ""
+	}
+	if filters.Topic != "" && !contains(topicOptions, filters.Topic) {
+		fail("topic", fmt.Sprintf("%q isn't a topic I know.", filters.Topic))
+		filters.Topic = ""
+	}
+	if filters.Difficulty != "" && !contains(difficultyOptions, filters.Difficulty) {
+		fail("difficulty", fmt.Sprintf("%q isn't a difficulty I know.", filters.Difficulty))
+		filters.Difficulty = ""
+	}
+	return filters, errs
+}
+
+// loadHistory gathers the recurser's sessions that pass the filters, newest
+// first. Dates are in the recurser's time zone.
+func loadHistory(recurser Recurser, filters historyFilters, store Store, ctx context.Context) (historyPage, error) {
+	page := historyPage{
+		Name:              recurser.Name,
+		Filters:           filters,
+		TopicOptions:      topicOptions,
+		DifficultyOptions: difficultyOptions,
+	}
+	loc := recurser.Config.location()
+
+	soloSessions, err := store.SoloSessions(ctx, recurser.Id)
+	if err != nil {
+		return page, err
+	}
+	pairingSessions, err := pairingHistory(recurser, store, ctx)
+	if err != nil {
+		return page, err
+	}
+
+	var ids []int
+	for _, session := range soloSessions {
+		ids = append(ids, session.Question)
+	}
+	for _, session := range pairingSessions {
+		ids = append(ids, session.Question)
+	}
+	questions := lookupQuestionIDs(ids, store, ctx)
+	question := func(id int) *Question {
+		if q, ok := questions[id]; ok {
+			return &q
+		}
+		return nil
+	}
+
+	page.SoloTotal = len(soloSessions)
+	for i := len(soloSessions) - 1; i >= 0; i-- {
+		session := soloSessions[i]
+		entry := soloEntry{
+			Date:     session.TimeStamp.In(loc).Format(dateLayout),
+			Question: question(session.Question),
+			Review:   session.Review,
+			Outcome:  fmtOutcome(session),
+		}
+		if filters.matches(entry.Date, entry.Question) {
+			page.Solo = append(page.Solo, entry)
+		}
+	}
+
+	names := make(map[string]string)
+	partnerName := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		names[id] = "Someone who has since left AlgoBot"
+		if partner, ok, err := store.GetRecurser(ctx, id); err != nil {
+			log.Println(err)
+		} else if ok {
+			names[id] = partner.Name
+		}
+		return names[id]
+	}
+
+	page.PairingTotal = len(pairingSessions)
+	for _, session := range pairingSessions {
+		entry := pairingEntry{
+			Date:      session.TimeStamp.In(loc).Format(dateLayout),
+			Role:      "Interviewee",
+			Question:  question(session.Question),
+			timeStamp: session.TimeStamp,
+		}
+		if session.Interviewer == recurser.Id {
+			entry.Role = "Interviewer"
+			entry.Partner = partnerName(session.Interviewee)
+		} else {
+			entry.Partner = partnerName(session.Interviewer)
+		}
+		if filters.matches(entry.Date, entry.Question) {
+			page.Pairing = append(page.Pairing, entry)
+		}
+	}
+	sort.SliceStable(page.Pairing, func(i, j int) bool {
+		return page.Pairing[i].timeStamp.After(page.Pairing[j].timeStamp)
+	})
+	return page, nil
+}
+
+// pairingHistory is every session the recurser was interviewed or
+// interviewed someone in. Sessions are kept in the interviewee's history, so
+// the ones they interviewed in come from their partners'. Partners who left
+// took those with them.
+func pairingHistory(recurser Recurser, store Store, ctx context.Context) ([]PairingSession, error) {
+	sessions, err := store.PairingSessions(ctx, recurser.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// interviews go both ways, and in threes round the rotation
+	var partners []string
+	for _, session := range sessions {
+		partners = addString(partners, session.Interviewer)
+		for _, id := range session.Rotation {
+			partners = addString(partners, id)
+		}
+	}
+	partners = removeString(partners, recurser.Id)
+
+	for _, partner := range partners {
+		theirs, err := store.PairingSessions(ctx, partner)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, session := range theirs {
+			if session.Interviewer == recurser.Id {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+	return sessions, nil
+}
+
+// fmtOutcome describes how a solo session went, e.g. "Solved in 25 minutes,
+// rated 4/5"
+func fmtOutcome(session SoloSession) string {
+	var outcome string
+	switch session.Outcome {
+	case outcomeSolved:
+		outcome = "Solved"
+		if session.Minutes > 0 {
+			outcome += fmt.Sprintf(" in %s", pluralize(session.Minutes, "minute"))
+		}
+	case outcomeGaveUp:
+		outcome = "Gave up"
+		if session.Minutes > 0 {
+			outcome += fmt.Sprintf(" after %s", pluralize(session.Minutes, "minute"))
+		}
+	default:
+		outcome = "No word yet"
+	}
+	if session.Rating > 0 {
+		outcome += fmt.Sprintf(", rated %d/5", session.Rating)
+	}
+	return outcome
+}
+
+func history(userID string, recurser Recurser, isSubscribed bool) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+	return fmt.Sprintf("[Click here to see your past sessions](%s) (the link works for a day)", pageLink("history", userID, time.Now()))
+}
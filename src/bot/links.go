@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Links to someone's config and history pages carry a token that's only
+// valid for that page and their ID until it expires. A token is
+// "<expiry>.<signature>", the expiry in Unix seconds and the signature an HMAC
+// of the page, the ID and the expiry, so nothing needs to be stored to check
+// one.
+const linkTTL = 24 * time.Hour
+
+var (
+	errLinkInvalid = errors.New("the link's token is missing or doesn't match")
+	errLinkExpired = errors.New("the link has expired")
+)
+
+// linkSecret signs links. It's the same for every instance of the bot so a
+// link works whichever one it reaches, and no link is valid until it's set.
+var linkSecret []byte
+
+// SetLinkSecret changes the key links are signed with
+func SetLinkSecret(secret []byte) {
+	linkSecret = secret
+}
+
+// LoadLinkSecret signs links with the secret kept in the store
+func LoadLinkSecret(s Store, ctx context.Context) error {
+	secret, err := s.LinkSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.New("the link secret is empty")
+	}
+	SetLinkSecret([]byte(secret))
+	return nil
+}
+
+func linkSignature(page string, id string, expires int64) string {
+	mac := hmac.New(sha256.New, linkSecret)
+	mac.Write([]byte(fmt.Sprintf("%s:%s:%d", page, id, expires)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signLink makes a token for the recurser's page that expires a day from now
+func signLink(page string, id string, now time.Time) string {
+	expires := now.Add(linkTTL).Unix()
+	return fmt.Sprintf("%d.%s", expires, linkSignature(page, id, expires))
+}
+
+// verifyLink checks a token made by signLink for the recurser's page
+func verifyLink(page string, id string, token string, now time.Time) error {
+	if len(linkSecret) == 0 {
+		return errLinkInvalid
+	}
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return errLinkInvalid
+	}
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return errLinkInvalid
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(linkSignature(page, id, expires))) {
+		return errLinkInvalid
+	}
+	if now.Unix() >= expires {
+		return errLinkExpired
+	}
+	return nil
+}
+
+// pageLink is the signed link to one of the recurser's pages, e.g. their
+// config page
+func pageLink(page string, id string, now time.Time) string {
+	return fmt.Sprintf("%s/%s/%s?token=%s", serverURL, page, id, signLink(page, id, now))
+}
+
+// authorizeLink checks the request's token for the recurser's page, writing
+// out a page explaining what's wrong if it doesn't check out
+func authorizeLink(w http.ResponseWriter, r *http.Request, page string, id string) bool {
+	err := verifyLink(page, id, r.FormValue("token"), time.Now())
+	if err == nil {
+		return true
+	}
+	log.Println(fmt.Sprintf("Rejected a %s link for %s: %v", page, id, err))
+
+	data := struct {
+		Title   string
+		Message string
+		Command string
+	}{"This link isn't valid", "It may have been copied wrong or changed.", page}
+	if err == errLinkExpired {
+		data.Title = "This link has expired"
+		data.Message = fmt.Sprintf("Links are only good for %s.", pluralize(int(linkTTL.Hours()), "hour"))
+	}
+
+	renderTemplate(w, http.StatusForbidden, "link_error.html", data)
+	return false
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+var difficultyLevels = map[string]int{
+	"easy":   0,
+	"medium": 1,
+	"hard":   2,
+}
+
+// How much each part of the compatibility score is worth. Every valid match
+// starts at baseCompatibility so that even pairs who have met before keep a
+// positive weight and are still preferred over leaving both people unmatched.
+// A single unmatched day outweighs every other difference between two
+// matches, so when someone has to sit out it's whoever waited the least.
+const (
+	baseCompatibility    = 10
+	experienceWeight     = 2 // per level closer in experience
+	pairingOverlapWeight = 2 // per shared pairing difficulty
+	environmentWeight    = 2
+	queueDayWeight       = 1 // per day each person has been waiting
+	maxQueueDays         = 7
+	unmatchedDayWeight   = 25 // per day in a row each person went unmatched
+	pairedBeforePenalty  = 8
+)
+
+// pairingLookback is how long two people have to wait before they can be
+// paired again, unless one of them asked for a rematch. Older pairings only
+// lower the score. Zero turns the rule off.
+var pairingLookback = 14 * 24 * time.Hour
+
+// SetPairingLookback changes how many days must pass before a repeat pairing
+func SetPairingLookback(days int) {
+	pairingLookback = time.Duration(days) * 24 * time.Hour
+}
+
+// A matcher splits the pool into pairs and whoever is left without a partner.
+// It must never pair two people canPair rules out.
+type matcher func(recursers []Recurser, history matchHistory) ([][2]Recurser, []Recurser)
+
+// matchers are the ways pairs can be chosen, by the name SetMatcher takes
+var matchers = map[string]matcher{
+	"weighted":  weightedMatching,
+	"roommates": stableMatching,
+}
+
+// pairingMatcher chooses each day's pairs, weighted matching unless
+// SetMatcher picks another
+var pairingMatcher matcher = weightedMatching
+
+// SetMatcher changes how pairs are chosen, e.g. "roommates"
+func SetMatcher(name string) error {
+	m, ok := matchers[name]
+	if !ok {
+		var names []string
+		for name := range matchers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%q isn't a matcher, choose one of %s", name, strings.Join(names, ", "))
+	}
+	pairingMatcher = m
+	return nil
+}
+
+// matchHistory is what the matcher knows about the pool beyond their configs
+type matchHistory struct {
+	now      time.Time
+	lookback time.Duration
+	// lastPaired[a][b] is when a and b last had a pairing session
+	lastPaired map[string]map[string]time.Time
+}
+
+func (h matchHistory) pairedBefore(a string, b string) bool {
+	_, ok := h.lastPaired[a][b]
+	return ok
+}
+
+// isRecentRepeat reports whether a and b paired within the lookback window
+// and neither of them asked to partner again
+func (h matchHistory) isRecentRepeat(a Recurser, b Recurser) bool {
+	last, ok := h.lastPaired[a.Id][b.Id]
+	if !ok || h.lookback <= 0 || wantsRematch(a, b) {
+		return false
+	}
+	return h.now.Sub(last) < h.lookback
+}
+
+func wantsRematch(a Recurser, b Recurser) bool {
+	return contains(a.RematchWith, b.Id) || contains(b.RematchWith, a.Id)
+}
+
+// loadMatchHistory looks up who in the pool has already paired with whom
+func loadMatchHistory(store Store, recursers []Recurser, now time.Time, ctx context.Context) matchHistory {
+	history := matchHistory{
+		now:        now,
+		lookback:   pairingLookback,
+		lastPaired: make(map[string]map[string]time.Time),
+	}
+
+	addPartners := func(a string, b string, when time.Time) {
+		if history.lastPaired[a] == nil {
+			history.lastPaired[a] = make(map[string]time.Time)
+		}
+		if when.After(history.lastPaired[a][b]) {
+			history.lastPaired[a][b] = when
+		}
+	}
+
+	for _, recurser := range recursers {
+		sessions, err := store.PairingSessions(ctx, recurser.Id)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, session := range sessions {
+			addPartners(session.Interviewer, session.Interviewee, session.TimeStamp)
+			addPartners(session.Interviewee, session.Interviewer, session.TimeStamp)
+		}
+	}
+
+	return history
+}
+
+// compatibility scores how good a match two recursers are; higher is better.
+// It rewards similar experience, overlapping pairing difficulties, a shared
+// interview environment and each person's priority in the queue, and penalizes
+// pairs that have interviewed each other before unless they asked for a rematch.
+func compatibility(a Recurser, b Recurser, history matchHistory) int {
+	weight := baseCompatibility
+
+	distance := difficultyLevels[a.Config.Experience] - difficultyLevels[b.Config.Experience]
+	if distance < 0 {
+		distance = -distance
+	}
+	weight += experienceWeight * (len(difficultyLevels) - 1 - distance)
+
+	for _, difficulty := range a.Config.PairingDifficulty {
+		if contains(b.Config.PairingDifficulty, difficulty) {
+			weight += pairingOverlapWeight
+		}
+	}
+
+	if a.Config.Environment == b.Config.Environment {
+		weight += environmentWeight
+	}
+
+	weight += priority(a, history.now) + priority(b, history.now)
+
+	if history.pairedBefore(a.Id, b.Id) && !wantsRematch(a, b) {
+		weight -= pairedBeforePenalty
+	}
+
+	return weight
+}
+
+// priority is how much the matcher favours finding this recurser a partner,
+// growing with their time in the queue and the days they've gone unmatched
+func priority(recurser Recurser, now time.Time) int {
+	days := daysQueued(recurser, now)
+	if days > maxQueueDays {
+		days = maxQueueDays
+	}
+	return queueDayWeight*days + unmatchedDayWeight*recurser.UnmatchedDays
+}
+
+// daysQueued is how many whole days the recurser has been waiting for a match
+func daysQueued(recurser Recurser, now time.Time) int {
+	if recurser.QueuedAt.IsZero() || now.Before(recurser.QueuedAt) {
+		return 0
+	}
+	return int(now.Sub(recurser.QueuedAt).Hours() / 24)
+}
+
+// canPair reports whether two recursers may interview each other today
+func canPair(a Recurser, b Recurser, history matchHistory) bool {
+	return isValidMatch(a, b) && !history.isRecentRepeat(a, b)
+}
+
+// weightedMatching pairs up as many recursers as possible and, out of all
+// the ways to do that, picks the one with the highest total compatibility.
+// Recent partners are never paired again.
+func weightedMatching(recursers []Recurser, history matchHistory) ([][2]Recurser, []Recurser) {
+	var edges []weightedEdge
+	for i := range recursers {
+		for j := i + 1; j < len(recursers); j++ {
+			if canPair(recursers[i], recursers[j], history) {
+				edges = append(edges, weightedEdge{i, j, compatibility(recursers[i], recursers[j], history)})
+			}
+		}
+	}
+
+	mate := maxWeightMatching(len(recursers), edges, true)
+
+	var pairs [][2]Recurser
+	var unmatched []Recurser
+	for i, j := range mate {
+		switch {
+		case j == -1:
+			unmatched = append(unmatched, recursers[i])
+		case i < j:
+			pairs = append(pairs, [2]Recurser{recursers[i], recursers[j]})
+		}
+	}
+	return pairs, unmatched
+}
+
+// formTriad adds someone left unmatched to one of the pairs, making a three
+// person rotation. It picks the most compatible combination where all three
+// can pair with each other and returns the pairs and unmatched that remain
+// along with the triad, or a nil triad if there's no such combination.
+func formTriad(pairs [][2]Recurser, unmatched []Recurser, history matchHistory) ([][2]Recurser, []Recurser, []Recurser) {
+	bestPair, bestExtra, bestWeight := -1, -1, 0
+
+	for i, pair := range pairs {
+		for j, extra := range unmatched {
+			if !canPair(pair[0], extra, history) || !canPair(pair[1], extra, history) {
+				continue
+			}
+			weight := compatibility(pair[0], extra, history) + compatibility(pair[1], extra, history)
+			if bestPair == -1 || weight > bestWeight {
+				bestPair, bestExtra, bestWeight = i, j, weight
+			}
+		}
+	}
+
+	if bestPair == -1 {
+		return pairs, unmatched, nil
+	}
+
+	triad := []Recurser{pairs[bestPair][0], pairs[bestPair][1], unmatched[bestExtra]}
+
+	remainingPairs := append([][2]Recurser{}, pairs[:bestPair]...)
+	remainingPairs = append(remainingPairs, pairs[bestPair+1:]...)
+	remainingUnmatched := append([]Recurser{}, unmatched[:bestExtra]...)
+	remainingUnmatched = append(remainingUnmatched, unmatched[bestExtra+1:]...)
+
+	return remainingPairs, remainingUnmatched, triad
+}
@@ -0,0 +1,449 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps everything in process memory. It behaves like the
+// Firestore backend as far as the bot is concerned (including array-contains
+// filters and array-union appends) which makes it handy for tests and for
+// running AlgoBot locally without a Google Cloud project.
+type MemoryStore struct {
+	mu              sync.Mutex
+	recursers       map[string]Recurser
+	soloSessions    map[string][]SoloSession
+	pairingSessions map[string][]PairingSession
+	questions       map[int]Question
+	problemSets     map[string][]int
+	dailyQuestions  map[string]DailyQuestion
+	jobRuns         map[string]time.Time
+	deliveries      map[string][]Delivery // by run
+	botToken        string
+	apiKey          string
+	linkSecret      string
+}
+
+// Fixtures is the JSON format accepted by LoadFixtures
+type Fixtures struct {
+	Recursers       []Recurser                  `json:"recursers"`
+	Questions       []Question                  `json:"questions"`
+	ProblemSets     map[string][]int            `json:"problemSets"`
+	SoloSessions    map[string][]SoloSession    `json:"soloSessions"`
+	PairingSessions map[string][]PairingSession `json:"pairingSessions"`
+	BotToken        string                      `json:"botToken"`
+	APIKey          string                      `json:"apiKey"`
+	LinkSecret      string                      `json:"linkSecret"`
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		recursers:       make(map[string]Recurser),
+		soloSessions:    make(map[string][]SoloSession),
+		pairingSessions: make(map[string][]PairingSession),
+		questions:       make(map[int]Question),
+		problemSets:     make(map[string][]int),
+		dailyQuestions:  make(map[string]DailyQuestion),
+		jobRuns:         make(map[string]time.Time),
+		deliveries:      make(map[string][]Delivery),
+	}
+}
+
+// LoadFixtures seeds the store from JSON. Recursers without an explicit
+// session history get an empty one, just like subscribing would give them.
+func (s *MemoryStore) LoadFixtures(r io.Reader) error {
+	var fixtures Fixtures
+	if err := json.NewDecoder(r).Decode(&fixtures); err != nil {
+		return err
+	}
+	s.Seed(fixtures)
+	return nil
+}
+
+// Seed adds the given fixtures to the store, overwriting anything with the same ID
+func (s *MemoryStore) Seed(fixtures Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, recurser := range fixtures.Recursers {
+		s.recursers[recurser.Id] = copyRecurser(recurser)
+		if _, ok := s.soloSessions[recurser.Id]; !ok {
+			s.soloSessions[recurser.Id] = []SoloSession{}
+		}
+		if _, ok := s.pairingSessions[recurser.Id]; !ok {
+			s.pairingSessions[recurser.Id] = []PairingSession{}
+		}
+	}
+	for _, question := range fixtures.Questions {
+		s.questions[question.Id] = question
+	}
+	for name, ids := range fixtures.ProblemSets {
+		s.problemSets[name] = append([]int{}, ids...)
+	}
+	for id, sessions := range fixtures.SoloSessions {
+		s.soloSessions[id] = append([]SoloSession{}, sessions...)
+	}
+	for id, sessions := range fixtures.PairingSessions {
+		s.pairingSessions[id] = append([]PairingSession{}, sessions...)
+	}
+	if fixtures.BotToken != "" {
+		s.botToken = fixtures.BotToken
+	}
+	if fixtures.APIKey != "" {
+		s.apiKey = fixtures.APIKey
+	}
+	if fixtures.LinkSecret != "" {
+		s.linkSecret = fixtures.LinkSecret
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) GetRecurser(ctx context.Context, id string) (Recurser, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurser, ok := s.recursers[id]
+	if !ok {
+		return Recurser{}, false, nil
+	}
+	return copyRecurser(recurser), true, nil
+}
+
+func (s *MemoryStore) PutRecurser(ctx context.Context, recurser Recurser) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recursers[recurser.Id] = copyRecurser(recurser)
+	return nil
+}
+
+func (s *MemoryStore) DeleteRecurser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.recursers, id)
+	return nil
+}
+
+func (s *MemoryStore) UpdateConfig(ctx context.Context, id string, config UserConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurser, ok := s.recursers[id]
+	if !ok {
+		return notFound("recursers", id)
+	}
+	recurser.Config = config
+	s.recursers[id] = copyRecurser(recurser)
+	return nil
+}
+
+func (s *MemoryStore) UpdateRecurser(ctx context.Context, id string, update RecurserUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurser, ok := s.recursers[id]
+	if !ok {
+		return notFound("recursers", id)
+	}
+	update.apply(&recurser)
+	s.recursers[id] = copyRecurser(recurser)
+	return nil
+}
+
+func (s *MemoryStore) PairingQueue(ctx context.Context) ([]Recurser, error) {
+	return s.filterRecursers(func(r Recurser) bool {
+		return r.IsPairingTomorrow
+	}), nil
+}
+
+func (s *MemoryStore) SoloRecipients(ctx context.Context, day string) ([]Recurser, error) {
+	return s.filterRecursers(func(r Recurser) bool {
+		return !r.IsSkippingTomorrow && contains(r.Config.SoloDays, day)
+	}), nil
+}
+
+func (s *MemoryStore) Skippers(ctx context.Context) ([]Recurser, error) {
+	return s.filterRecursers(func(r Recurser) bool {
+		return r.IsSkippingTomorrow
+	}), nil
+}
+
+// filterRecursers returns matching recursers ordered by ID so results are stable
+func (s *MemoryStore) filterRecursers(keep func(Recurser) bool) []Recurser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recursersList []Recurser
+	for _, recurser := range s.recursers {
+		if keep(recurser) {
+			recursersList = append(recursersList, copyRecurser(recurser))
+		}
+	}
+	sort.Slice(recursersList, func(i, j int) bool {
+		return recursersList[i].Id < recursersList[j].Id
+	})
+	return recursersList
+}
+
+func (s *MemoryStore) CreateSessionHistory(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.soloSessions[id]; ok {
+		return alreadyExists("soloSessions", id)
+	}
+	if _, ok := s.pairingSessions[id]; ok {
+		return alreadyExists("pairingSessions", id)
+	}
+	s.soloSessions[id] = []SoloSession{}
+	s.pairingSessions[id] = []PairingSession{}
+	return nil
+}
+
+func (s *MemoryStore) DeleteSessionHistory(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.soloSessions, id)
+	delete(s.pairingSessions, id)
+	return nil
+}
+
+func (s *MemoryStore) AppendSoloSession(ctx context.Context, id string, session SoloSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, ok := s.soloSessions[id]
+	if !ok {
+		return notFound("soloSessions", id)
+	}
+	// array-union semantics: identical elements are only stored once
+	for _, existing := range sessions {
+		if reflect.DeepEqual(existing, session) {
+			return nil
+		}
+	}
+	s.soloSessions[id] = append(sessions, session)
+	return nil
+}
+
+func (s *MemoryStore) UpdateLastSoloSession(ctx context.Context, id string, session SoloSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := s.soloSessions[id]
+	if len(sessions) == 0 {
+		return notFound("soloSessions", id)
+	}
+	sessions[len(sessions)-1] = session
+	return nil
+}
+
+func (s *MemoryStore) AppendPairingSession(ctx context.Context, id string, session PairingSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, ok := s.pairingSessions[id]
+	if !ok {
+		return notFound("pairingSessions", id)
+	}
+	for _, existing := range sessions {
+		if reflect.DeepEqual(existing, session) {
+			return nil
+		}
+	}
+	s.pairingSessions[id] = append(sessions, session)
+	return nil
+}
+
+func (s *MemoryStore) SoloSessions(ctx context.Context, id string) ([]SoloSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]SoloSession{}, s.soloSessions[id]...), nil
+}
+
+func (s *MemoryStore) PairingSessions(ctx context.Context, id string) ([]PairingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]PairingSession{}, s.pairingSessions[id]...), nil
+}
+
+func (s *MemoryStore) Questions(ctx context.Context, query QuestionQuery) ([]Question, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var questions []Question
+	for _, question := range s.questions {
+		if query.matches(question) {
+			questions = append(questions, question)
+		}
+	}
+	sort.Slice(questions, func(i, j int) bool {
+		return questions[i].Id < questions[j].Id
+	})
+	return questions, nil
+}
+
+func (s *MemoryStore) ProblemSet(ctx context.Context, name string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, ok := s.problemSets[name]
+	if !ok {
+		return nil, nil
+	}
+	return append([]int{}, ids...), nil
+}
+
+func (s *MemoryStore) CreateDailyQuestion(ctx context.Context, date string, daily DailyQuestion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.dailyQuestions[date]; ok {
+		return alreadyExists("dailyQuestions", date)
+	}
+	s.dailyQuestions[date] = daily
+	return nil
+}
+
+func (s *MemoryStore) LastJobRun(ctx context.Context, job string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.jobRuns[job], nil
+}
+
+func (s *MemoryStore) RecordJobRun(ctx context.Context, job string, last time.Time, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.jobRuns[job].Equal(last) {
+		return false, nil
+	}
+	s.jobRuns[job] = at
+	return true, nil
+}
+
+func (s *MemoryStore) ClaimDelivery(ctx context.Context, delivery Delivery) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, claimed := range s.deliveries[delivery.Run] {
+		if claimed.Recipient == delivery.Recipient {
+			return false, nil
+		}
+	}
+	s.deliveries[delivery.Run] = append(s.deliveries[delivery.Run], delivery)
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseDelivery(ctx context.Context, run string, recipient string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []Delivery
+	for _, claimed := range s.deliveries[run] {
+		if claimed.Recipient != recipient {
+			kept = append(kept, claimed)
+		}
+	}
+	s.deliveries[run] = kept
+	return nil
+}
+
+func (s *MemoryStore) Deliveries(ctx context.Context, run string) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Delivery{}, s.deliveries[run]...), nil
+}
+
+// DailyQuestion returns the daily question recorded for the given date
+func (s *MemoryStore) DailyQuestion(date string) (DailyQuestion, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	daily, ok := s.dailyQuestions[date]
+	return daily, ok
+}
+
+func (s *MemoryStore) BotToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.botToken == "" {
+		return "", errors.New("no bot token has been set")
+	}
+	return s.botToken, nil
+}
+
+func (s *MemoryStore) APIKey(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.apiKey == "" {
+		return "", errors.New("no API key has been set")
+	}
+	return s.apiKey, nil
+}
+
+func (s *MemoryStore) LinkSecret(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.linkSecret == "" {
+		return "", errors.New("no link secret has been set")
+	}
+	return s.linkSecret, nil
+}
+
+func (q QuestionQuery) matches(question Question) bool {
+	if q.Id != 0 && question.Id != q.Id {
+		return false
+	}
+	if q.Difficulty != "" && question.Difficulty != q.Difficulty {
+		return false
+	}
+	if q.Tag != "" && !contains(question.Tags, q.Tag) {
+		return false
+	}
+	if q.ProblemSet != "" && q.ProblemSet != "random" && !contains(question.Psets, q.ProblemSet) {
+		return false
+	}
+	return true
+}
+
+// copyRecurser makes sure callers never share slices with what's stored
+func copyRecurser(recurser Recurser) Recurser {
+	config := recurser.Config
+	config.Topics = append([]string{}, config.Topics...)
+	config.SoloDays = append([]string{}, config.SoloDays...)
+	config.SoloDifficulty = append([]string{}, config.SoloDifficulty...)
+	config.PairingDifficulty = append([]string{}, config.PairingDifficulty...)
+	recurser.Config = config
+	recurser.RematchWith = append([]string{}, recurser.RematchWith...)
+	recurser.SkipDates = append([]string{}, recurser.SkipDates...)
+	recurser.Pauses = append([]Pause{}, recurser.Pauses...)
+	return recurser
+}
+
+func notFound(collection string, id string) error {
+	return fmt.Errorf("%s/%s: document not found", collection, id)
+}
+
+func alreadyExists(collection string, id string) error {
+	return fmt.Errorf("%s/%s: document already exists", collection, id)
+}
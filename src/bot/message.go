@@ -7,13 +7,15 @@ import (
 )
 
 type Messenger struct {
-	Help          string `json:"help"`
+	HelpIntro     string `json:"helpIntro"`
+	HelpFooter    string `json:"helpFooter"`
 	Subscribe     string `json:"subscribe"`
 	Unsubscribe   string `json:"unsubscribe"`
 	NotSubscribed string `json:"notSubscribed"`
 	NotConfigured string `json:"notConfigured"`
 	NotMatched    string `json:"notMatched"`
 	Matched       string `json:"matched"`
+	MatchedTriad  string `json:"matchedTriad"`
 	WriteError    string `json:"writeError"`
 	ReadError     string `json:"readError"`
 }
@@ -4,20 +4,79 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
 	"strings"
-	"sync"
 	"time"
-
-	"cloud.google.com/go/firestore"
 )
 
-func MessagePairs(client *firestore.Client, ctx context.Context) {
-	iter := client.Collection("recursers").Where("isPairingTomorrow", "==", true).Documents(ctx)
-	recursersList := iterToRecurserList(iter)
+// MessagePairs matches everyone in the pairing queue and messages them. The
+// run ledger keeps a retried run from matching or messaging anyone twice.
+func MessagePairs(store Store, zulip ZulipSender, now time.Time, ctx context.Context) {
+	run := runKey("pairs", now.UTC().Format(dateLayout))
+
+	queue, err := store.PairingQueue(ctx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// anyone an earlier run got to was already matched or told they weren't,
+	// and anyone who paused pairing stays queued for when they're back
+	done, err := store.Deliveries(ctx, run)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// anyone whose message fails is given back to a retry
+	var failed []string
+	defer func() {
+		if len(failed) > 0 {
+			log.Panicf("Pairing messages didn't go out to %s", strings.Join(failed, ", "))
+		}
+	}()
+
+	// an earlier run may have matched a group but not got everyone their
+	// interviewer instructions
+	for _, delivery := range done {
+		if !strings.HasPrefix(delivery.Recipient, groupPrefix) {
+			continue
+		}
+		group := strings.Split(strings.TrimPrefix(delivery.Recipient, groupPrefix), ",")
+		var rotation []string
+		if len(group) == 3 {
+			rotation = group
+		}
+		for i := range group {
+			if delivered(done, interviewerRecipient(group[i])) {
+				continue
+			}
+			interviewer, _, err := store.GetRecurser(ctx, group[i])
+			if err != nil {
+				log.Println(err)
+				failed = append(failed, group[i])
+				continue
+			}
+			interviewee, _, err := store.GetRecurser(ctx, group[(i+1)%len(group)])
+			if err != nil {
+				log.Println(err)
+				failed = append(failed, interviewer.Name)
+				continue
+			}
+			if !assignInterview(store, zulip, run, interviewer, interviewee, rotation, now, ctx) {
+				failed = append(failed, interviewer.Name)
+			}
+		}
+	}
+
+	var recursersList []Recurser
+	for _, recurser := range queue {
+		if recurser.isPaused(now, true) {
+			log.Println(fmt.Sprintf("%s is paused from pairing today", recurser.Name))
+			continue
+		}
+		if !delivered(done, recurser.Id) {
+			recursersList = append(recursersList, recurser)
+		}
+	}
 
 	// if for some reason there's no matches today, we're done
 	if len(recursersList) == 0 {
@@ -28,9 +87,10 @@ func MessagePairs(client *firestore.Client, ctx context.Context) {
 	// shuffle our recursers. This will not error if the list is empty
 	shuffle(recursersList)
 
-	optimalPath, err := determineBestPath(recursersList)
+	history := loadMatchHistory(store, recursersList, now, ctx)
+	optimalPath, err := determineBestPath(recursersList, history)
 	if err != nil {
-		log.Fatal("Pairing should not occur for invalid pools")
+		log.Panic("Pairing should not occur for invalid pools")
 	}
 
 	pairedList, notPairedList, err := determinePairs(optimalPath)
@@ -39,130 +99,173 @@ func MessagePairs(client *firestore.Client, ctx context.Context) {
 	}
 
 	// message the peeps!
-	doc, err := client.Collection("auth").Doc("api").Get(ctx)
-	if err != nil {
-		log.Panic(err)
-	}
-
-	apikey := doc.Data()["key"]
-	botPassword := apikey.(string)
-	zulipClient := &http.Client{}
-
 	// if there's an odd number today, message the last person in the list
 	// and tell them they don't get a match today, then knock them off the list
 	for i := 0; i < len(notPairedList); i++ {
 		recurser := notPairedList[i]
+		if !claimDeliveries(store, run, []string{recurser.Id}, now, ctx) {
+			continue
+		}
 		log.Println(fmt.Sprintf("%s was not paired today", recurser.Name))
-		messageRequest := url.Values{}
-		messageRequest.Add("type", "private")
-		messageRequest.Add("to", recurser.Email)
-		messageRequest.Add("content", botMessages.NotMatched)
-		req, err := http.NewRequest("POST", zulipAPIURL, strings.NewReader(messageRequest.Encode()))
-		req.SetBasicAuth(botEmailAddress, botPassword)
-		req.Header.Set("content-type", "application/x-www-form-urlencoded")
-		resp, err := zulipClient.Do(req)
+		_, err := zulip.SendPrivate([]string{recurser.Email}, botMessages.NotMatched)
 		if err != nil {
-			log.Panic(err)
+			log.Println(err)
+			releaseDeliveries(store, run, []string{recurser.Id}, ctx)
+			failed = append(failed, recurser.Name)
+			continue
 		}
-		defer resp.Body.Close()
-		respBodyText, err := ioutil.ReadAll(resp.Body)
+
+		// they stay in the queue, but with a better shot at a match tomorrow
+		unmatchedDays := recurser.UnmatchedDays + 1
+		err = store.UpdateRecurser(ctx, recurser.Id, RecurserUpdate{UnmatchedDays: &unmatchedDays})
 		if err != nil {
 			log.Println(err)
 		}
-		log.Println(string(respBodyText))
 	}
 
-	// Send out messages notifying pairs that they've been matched
+	// Send out messages notifying pairs that they've been matched, then each
+	// of them the question they should prepare for their partner
 	for i := 0; i < len(pairedList); i += 2 {
-		messageRequest := url.Values{}
-		messageRequest.Add("type", "private")
-		messageRequest.Add("to", pairedList[i].Email+", "+pairedList[i+1].Email)
-		messageRequest.Add("content", botMessages.Matched)
-		req, err := http.NewRequest("POST", zulipAPIURL, strings.NewReader(messageRequest.Encode()))
-		req.SetBasicAuth(botEmailAddress, botPassword)
-		req.Header.Set("content-type", "application/x-www-form-urlencoded")
-		resp, err := zulipClient.Do(req)
-		if err != nil {
-			log.Panic(err)
+		pair := []string{pairedList[i].Id, pairedList[i+1].Id, groupRecipient(pairedList[i].Id, pairedList[i+1].Id)}
+		if !claimDeliveries(store, run, pair, now, ctx) {
+			log.Println(fmt.Sprintf("%s & %s were already messaged today", pairedList[i].Name, pairedList[i+1].Name))
+			continue
 		}
-		defer resp.Body.Close()
-		respBodyText, err := ioutil.ReadAll(resp.Body)
+
+		_, err := zulip.SendPrivate([]string{pairedList[i].Email, pairedList[i+1].Email}, botMessages.Matched)
 		if err != nil {
 			log.Println(err)
+			releaseDeliveries(store, run, pair, ctx)
+			failed = append(failed, pairedList[i].Name, pairedList[i+1].Name)
+			continue
 		}
-		log.Println(string(respBodyText))
 		log.Println(fmt.Sprintf("A match went out: %s & %s", pairedList[i].Name, pairedList[i+1].Name))
-	}
 
-	// Send private messages to each individual about the question they should prepare for their partner
-	for i := range pairedList {
-		interviewer := pairedList[i]
-		var interviewee Recurser
+		// Interviews go both ways so interviewers become interviewees and vice versa
+		for _, interview := range [][2]Recurser{{pairedList[i], pairedList[i+1]}, {pairedList[i+1], pairedList[i]}} {
+			if !assignInterview(store, zulip, run, interview[0], interview[1], nil, now, ctx) {
+				failed = append(failed, interview[0].Name)
+			}
+		}
+	}
 
-		// Interviews go both ways so we need to ensure interviewers become interviewees and vice versa
-		if i%2 == 0 {
-			interviewee = pairedList[i+1]
+	// An odd pool may have one group of three that interview each other in turn
+	if triad := optimalPath.triad; len(triad) == 3 {
+		rotation := []string{triad[0].Id, triad[1].Id, triad[2].Id}
+		emails := []string{triad[0].Email, triad[1].Email, triad[2].Email}
+		group := append([]string{groupRecipient(rotation...)}, rotation...)
+		if !claimDeliveries(store, run, group, now, ctx) {
+			log.Println(fmt.Sprintf("%s, %s & %s were already messaged today", triad[0].Name, triad[1].Name, triad[2].Name))
+		} else if _, err := zulip.SendPrivate(emails, fmtTriadMessage(triad)); err != nil {
+			log.Println(err)
+			releaseDeliveries(store, run, group, ctx)
+			failed = append(failed, triad[0].Name, triad[1].Name, triad[2].Name)
 		} else {
-			interviewee = pairedList[i-1]
+			log.Println(fmt.Sprintf("A triad went out: %s, %s & %s", triad[0].Name, triad[1].Name, triad[2].Name))
+
+			for i := range triad {
+				if !assignInterview(store, zulip, run, triad[i], triad[(i+1)%3], rotation, now, ctx) {
+					failed = append(failed, triad[i].Name)
+				}
+			}
 		}
+	}
+}
 
-		messageRequest := url.Values{}
-		messageRequest.Add("type", "private")
-		messageRequest.Add("to", interviewer.Email)
+// groupPrefix marks the ledger's record of a match, which lists who was
+// matched in the order they interview each other
+const groupPrefix = "group:"
 
-		question := selectQuestion(interviewee, client, ctx)
-		msg := fmtInterviewerMessage(question, interviewee)
-		messageRequest.Add("content", msg)
+func groupRecipient(ids ...string) string {
+	return groupPrefix + strings.Join(ids, ",")
+}
 
-		req, err := http.NewRequest("POST", zulipAPIURL, strings.NewReader(messageRequest.Encode()))
-		req.SetBasicAuth(botEmailAddress, botPassword)
-		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+// interviewerRecipient is the ledger's recipient for an interviewer's instructions
+func interviewerRecipient(id string) string {
+	return id + ":interviewer"
+}
 
-		resp, err := zulipClient.Do(req)
-		if err != nil {
-			log.Panic(err)
-		}
-		defer resp.Body.Close()
+// assignInterview sends the interviewer their question, records the session
+// for the interviewee and takes the interviewer out of the queue. It reports
+// false if the instructions didn't go out, leaving them to a retry.
+func assignInterview(store Store, zulip ZulipSender, run string, interviewer Recurser, interviewee Recurser, rotation []string, now time.Time, ctx context.Context) bool {
+	recipient := []string{interviewerRecipient(interviewer.Id)}
+	if !claimDeliveries(store, run, recipient, now, ctx) {
+		log.Println(fmt.Sprintf("%s already got their interview instructions", interviewer.Name))
+		return true
+	}
 
-		respBodyText, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println(string(respBodyText))
-			log.Println(fmt.Sprintf("Interview instructions went out to %s", interviewer.Name))
+	// interviewees that pick their own question let their interviewer know directly
+	var question *Question
+	if !interviewee.Config.ManualQuestion {
+		var repeat bool
+		question, repeat = selectQuestion(interviewee, true, store, now, ctx)
+		if repeat {
+			log.Println(fmt.Sprintf("%s has been sent every question matching their config", interviewee.Name))
 		}
+	}
+	msg := fmtInterviewerMessage(question, interviewee)
 
-		session := map[string]interface{}{
-			"interviewer": interviewer.Id,
-			"interviewee": interviewee.Id,
-			"question":    question["id"],
-			"timeStamp":   time.Now(),
-		}
+	_, err := zulip.SendPrivate([]string{interviewer.Email}, msg)
+	if err != nil {
+		log.Println(err)
+		releaseDeliveries(store, run, recipient, ctx)
+		return false
+	}
+	log.Println(fmt.Sprintf("Interview instructions went out to %s", interviewer.Name))
 
-		doc := client.Collection("pairingSessions").Doc(interviewee.Id)
-		_, err = doc.Update(ctx, []firestore.Update{{Path: "sessions", Value: firestore.ArrayUnion(session)}})
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println(fmt.Sprintf("A session was recorded: %s & %s", interviewer.Name, interviewee.Name))
-		}
+	session := PairingSession{
+		Interviewer: interviewer.Id,
+		Interviewee: interviewee.Id,
+		TimeStamp:   now,
+		Rotation:    rotation,
+	}
+	if question != nil {
+		session.Question = question.Id
+	}
 
-		// Upon having an interview, kick out of queue
-		// We require manual sign-ups to prevent people from forgetting and ruining someone else's prep
-		doc = client.Collection("recursers").Doc(interviewer.Id)
-		_, err = doc.Update(ctx, []firestore.Update{{Path: "isPairingTomorrow", Value: false}})
-		if err != nil {
-			log.Println(err)
-		} else {
-			log.Println(fmt.Sprintf("%s was kicked from pairing queue", interviewer.Name))
-		}
+	err = store.AppendPairingSession(ctx, interviewee.Id, session)
+	if err != nil {
+		log.Println(err)
+	} else {
+		log.Println(fmt.Sprintf("A session was recorded: %s & %s", interviewer.Name, interviewee.Name))
+	}
+
+	// Upon having an interview, kick out of queue
+	// We require manual sign-ups to prevent people from forgetting and ruining someone else's prep
+	pairing, queuedAt, unmatchedDays := false, time.Time{}, 0
+	rematchWith := removeString(interviewer.RematchWith, interviewee.Id)
+	err = store.UpdateRecurser(ctx, interviewer.Id, RecurserUpdate{
+		IsPairingTomorrow: &pairing,
+		QueuedAt:          &queuedAt,
+		UnmatchedDays:     &unmatchedDays,
+		RematchWith:       &rematchWith,
+	})
+	if err != nil {
+		log.Println(err)
+	} else {
+		log.Println(fmt.Sprintf("%s was kicked from pairing queue", interviewer.Name))
+	}
+	return true
+}
+
+// fmtTriadMessage introduces a group of three and spells out who interviews whom
+func fmtTriadMessage(triad []Recurser) string {
+	var rotation strings.Builder
+	for i := range triad {
+		rotation.WriteString(fmt.Sprintf("* %s interviews %s\n", triad[i].Name, triad[(i+1)%len(triad)].Name))
 	}
+	return fmt.Sprintf(botMessages.MatchedTriad, rotation.String())
 }
 
-func fmtInterviewerMessage(question map[string]interface{}, interviewee Recurser) string {
+func fmtInterviewerMessage(question *Question, interviewee Recurser) string {
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("Here's what you need to know as the interviewer when you pair with %s:\n\n", interviewee.Name))
-	builder.WriteString(fmt.Sprintf("[Your question to prepare](%s)\n", question["url"]))
+	if question != nil {
+		builder.WriteString(fmt.Sprintf("[Your question to prepare](%s)\n", question.URL))
+	} else {
+		builder.WriteString(fmt.Sprintf("%s will be sending you the question to prepare.\n", interviewee.Name))
+	}
 	builder.WriteString("Try to learn multiple solutions, starting from brute force and ending with the optimal algorithm.\n\n")
 	builder.WriteString(fmt.Sprintf("Please conduct the interview on %s.\n\n", interviewee.Config.Environment))
 	builder.WriteString(fmt.Sprintf("Here are some additional notes from your interviewee: %s\n\n", interviewee.Config.Comments))
@@ -170,104 +273,45 @@ func fmtInterviewerMessage(question map[string]interface{}, interviewee Recurser
 	return builder.String()
 }
 
+// Path is the pool in the order it should be paired off: matched pairs are
+// adjacent and come first, followed by anyone left unmatched. In an odd pool
+// three people may instead form a triad, which isn't part of the order.
 type Path struct {
 	order      []Recurser
 	validPairs int
+	triad      []Recurser
 }
 
-func determineBestPath(recursers []Recurser) (Path, error) {
-	stack := make([]Path, 0)
-	for _, recurser := range recursers {
-		stack = append(stack, Path{[]Recurser{recurser}, 0})
-	}
-
-	if len(stack) == 0 {
+// determineBestPath matches the pool with the chosen matcher, by default a
+// maximum weight matching over everyone's compatibility (see matching.go)
+func determineBestPath(recursers []Recurser, history matchHistory) (Path, error) {
+	if len(recursers) == 0 {
 		return Path{}, errors.New("Empty pool for pairing")
 	}
 
-	bestPath := &Path{recursers, 0}
-	wg := new(sync.WaitGroup)
-	bestPossibleScore := len(recursers) / 2
-
-	for i := range stack {
-		wg.Add(1)
-		go func(path Path) {
-			getNext(path, recursers, map[string]bool{path.order[0].Id: true}, bestPath, bestPossibleScore, wg)
-			defer wg.Done()
-		}(stack[i])
-	}
-	wg.Wait()
-
-	return *bestPath, nil
-}
+	pairs, unmatched := pairingMatcher(recursers, history)
 
-func getNext(path Path, recursers []Recurser, seen map[string]bool, bestPath *Path, bestPossibleScore int, wg *sync.WaitGroup) {
-	if len(path.order)%2 == 0 && isValidSoFar(path.order) {
-		path.validPairs++
-	}
-
-	if path.validPairs > bestPath.validPairs && len(path.order) == len(recursers) {
-		*bestPath = path
+	var triad []Recurser
+	if len(recursers)%2 != 0 {
+		pairs, unmatched, triad = formTriad(pairs, unmatched, history)
 	}
 
-	if bestPath.validPairs == bestPossibleScore || len(path.order) == len(recursers) {
-		return
+	order := make([]Recurser, 0, len(recursers))
+	for _, pair := range pairs {
+		order = append(order, pair[0], pair[1])
 	}
+	order = append(order, unmatched...)
 
-	for _, recurser := range recursers {
-		if _, ok := seen[recurser.Id]; ok {
-			continue
-		}
-
-		pathCopy := path
-		pathCopy.order = make([]Recurser, len(path.order))
-		copy(pathCopy.order, path.order)
-		pathCopy.order = append(pathCopy.order, recurser)
-
-		seenCopy := make(map[string]bool, 0)
-		for k, v := range seen {
-			seenCopy[k] = v
-		}
-		seenCopy[recurser.Id] = true
-
-		wg.Add(1)
-		go func() {
-			getNext(pathCopy, recursers, seenCopy, bestPath, bestPossibleScore, wg)
-			defer wg.Done()
-		}()
-	}
-}
-
-func isValidSoFar(recursers []Recurser) bool {
-	recurserOne := recursers[len(recursers)-2]
-	recurserTwo := recursers[len(recursers)-1]
-	return isValidMatch(recurserOne, recurserTwo)
+	return Path{order, len(pairs), triad}, nil
 }
 
+// determinePairs splits the path into the people matched with a partner,
+// two by two, and those left without one. The pairs are exactly the ones the
+// matcher chose, so nobody it kept apart (e.g. recent partners) is paired.
 func determinePairs(path Path) ([]Recurser, []Recurser, error) {
-	count := path.validPairs
-	recursers := path.order
-	var paired []Recurser
-	var notPaired []Recurser
-
-	if len(recursers)%2 != 0 {
-		notPaired = append(notPaired, recursers[len(recursers)-1])
-		recursers = recursers[:len(recursers)-1]
-	}
-
-	for i := 1; i < len(recursers); i += 2 {
-		if isValidMatch(recursers[i-1], recursers[i]) {
-			paired = append(paired, recursers[i-1], recursers[i])
-			count -= 1
-		} else {
-			notPaired = append(notPaired, recursers[i-1], recursers[i])
-		}
+	split := 2 * path.validPairs
+	if split > len(path.order) {
+		return nil, path.order, errors.New("Did not find all valid pairs")
 	}
-
-	var err error
-	if count != 0 {
-		err = errors.New("Did not find all valid pairs")
-	}
-
-	return paired, notPaired, err
+	return path.order[:split], path.order[split:], nil
 }
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxPauseDays is the longest anyone can pause for in one go
+const maxPauseDays = 365
+
+// Pause is a stretch of days, in the recurser's time zone, without solo
+// questions and, if Pairing is set, without pairing matches
+type Pause struct {
+	Start   string `structs:"start" firestore:"start"`     // first day paused (YYYY-MM-DD)
+	End     string `structs:"end" firestore:"end"`         // last day paused (YYYY-MM-DD)
+	Pairing bool   `structs:"pairing" firestore:"pairing"` // also sit out of pairing matches
+}
+
+func (p Pause) covers(date string, pairing bool) bool {
+	return p.Start <= date && date <= p.End && (p.Pairing || !pairing)
+}
+
+// localDate is the recurser's date at the given time, e.g. "2026-10-19"
+func (r Recurser) localDate(now time.Time) string {
+	return now.In(r.Config.location()).Format(dateLayout)
+}
+
+// isPaused reports whether the recurser is paused at the given time, for
+// solo questions or, with pairing set, for pairing
+func (r Recurser) isPaused(now time.Time, pairing bool) bool {
+	date := r.localDate(now)
+	for _, pause := range r.Pauses {
+		if pause.covers(date, pairing) {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeDate is the first day after the pause the recurser is in at the given
+// time, following on through pauses that back onto it, or "" if they aren't
+// paused
+func (r Recurser) resumeDate(now time.Time, pairing bool) string {
+	date := r.localDate(now)
+	resume := ""
+	for extended := true; extended; {
+		extended = false
+		for _, pause := range r.Pauses {
+			if pause.covers(date, pairing) {
+				date = nextDate(pause.End)
+				resume = date
+				extended = true
+			}
+		}
+	}
+	return resume
+}
+
+// nextDate is the day after a YYYY-MM-DD date
+func nextDate(date string) string {
+	t, _ := time.Parse(dateLayout, date)
+	return t.AddDate(0, 0, 1).Format(dateLayout)
+}
+
+// fmtDate writes a YYYY-MM-DD date out, e.g. "Monday, November 2"
+func fmtDate(date string) string {
+	t, _ := time.Parse(dateLayout, date)
+	return t.Format("Monday, January 2")
+}
+
+// pause stops the recurser's solo questions, and optionally their pairing
+// matches, from today until the day they resume
+func pause(userID string, recurser Recurser, isSubscribed bool, until string, pairing bool, now time.Time, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	today := recurser.localDate(now)
+	if until <= today {
+		return "You can only pause until a day after today."
+	}
+	if until > pauseDays(recurser, strconv.Itoa(maxPauseDays), now) {
+		return fmt.Sprintf("You can pause for up to %d days at a time.", maxPauseDays)
+	}
+
+	// pauses that are over are forgotten
+	pauses := []Pause{}
+	for _, p := range recurser.Pauses {
+		if p.End >= today {
+			pauses = append(pauses, p)
+		}
+	}
+	end, _ := time.Parse(dateLayout, until)
+	recurser.Pauses = append(pauses, Pause{Start: today, End: end.AddDate(0, 0, -1).Format(dateLayout), Pairing: pairing})
+
+	err := store.PutRecurser(ctx, recurser)
+	if err != nil {
+		return botMessages.WriteError
+	}
+
+	what := "solo questions"
+	if pairing {
+		what = "solo questions or pairing matches"
+	}
+	return fmt.Sprintf("Paused! Enjoy the break :) **I will not send you** %s until %s. Use `resume` to come back early.",
+		what, fmtDate(recurser.resumeDate(now, pairing)))
+}
+
+// pauseDays is how the resume date is worked out for `pause <n> days`
+func pauseDays(recurser Recurser, days string, now time.Time) string {
+	n, _ := strconv.Atoi(days)
+	today, _ := time.Parse(dateLayout, recurser.localDate(now))
+	return today.AddDate(0, 0, n).Format(dateLayout)
+}
+
+func resume(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+	if len(recurser.Pauses) == 0 {
+		return "You aren't paused, so there's nothing to resume!"
+	}
+
+	recurser.Pauses = []Pause{}
+	err := store.PutRecurser(ctx, recurser)
+	if err != nil {
+		return botMessages.WriteError
+	}
+	return "Welcome back! Your pauses are cancelled and **I will contact you** as usual :)"
+}
+
+// validatePauseDays accepts a number of days to pause for
+func validatePauseDays(days string) error {
+	n, err := strconv.Atoi(days)
+	if err != nil {
+		return fmt.Errorf("%q isn't a number of days.", days)
+	}
+	if n < 1 || n > maxPauseDays {
+		return fmt.Errorf("You can pause for between 1 and %d days at a time.", maxPauseDays)
+	}
+	return nil
+}
+
+// validatePauseUntil accepts the date someone comes back from a pause. Whether
+// it's after today depends on the recurser's time zone, so pause checks that.
+func validatePauseUntil(date string) error {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("%q isn't a date like 2026-11-02.", date)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Spaced repetition follows SuperMemo's SM-2: every rated attempt at a
+// question moves its next review further out, by a factor that grows with
+// good ratings and shrinks with poor ones. Ratings of 1 or 2 start it over.
+const (
+	initialEase   = 2.5
+	minEase       = 1.3
+	passingRating = 3
+	maxRating     = 5
+	// what an unrated attempt counts as when we at least know how it ended
+	solvedRating = 4
+	gaveUpRating = 1
+)
+
+// reviewCard is where a question stands in the recurser's review schedule
+type reviewCard struct {
+	question    int
+	repetitions int     // successful reviews in a row
+	interval    int     // days between the last attempt and the next review
+	ease        float64 // how quickly the interval grows
+	lastRating  int     // the rating given to the last rated attempt, if any
+	lastOutcome string  // how the last attempt with a rating or outcome ended
+	lastSeen    time.Time
+	due         time.Time
+}
+
+// attemptRating is the session's rating or, failing that, what its outcome
+// says about how it went. It's 0 if we know nothing.
+func attemptRating(session SoloSession) int {
+	switch {
+	case session.Rating != 0:
+		return session.Rating
+	case session.Outcome == outcomeSolved:
+		return solvedRating
+	case session.Outcome == outcomeGaveUp:
+		return gaveUpRating
+	}
+	return 0
+}
+
+// reviewSchedule replays the recurser's solo sessions, oldest first, through
+// SM-2. Only questions with a rated or finished attempt get a card.
+func reviewSchedule(sessions []SoloSession) map[int]*reviewCard {
+	cards := make(map[int]*reviewCard)
+	for _, session := range sessions {
+		rating := attemptRating(session)
+		card, ok := cards[session.Question]
+		if !ok {
+			if rating == 0 {
+				continue
+			}
+			card = &reviewCard{question: session.Question, ease: initialEase}
+			cards[session.Question] = card
+		}
+
+		// an attempt we know nothing about only restarts the clock
+		if rating != 0 {
+			card.rate(rating)
+			card.lastRating = session.Rating
+			card.lastOutcome = session.Outcome
+		}
+		card.lastSeen = session.TimeStamp
+		card.due = session.TimeStamp.AddDate(0, 0, card.interval)
+	}
+	return cards
+}
+
+// rate updates the card after an attempt rated from 1 to 5
+func (c *reviewCard) rate(rating int) {
+	if rating >= passingRating {
+		switch c.repetitions {
+		case 0:
+			c.interval = 1
+		case 1:
+			c.interval = 6
+		default:
+			c.interval = int(math.Round(float64(c.interval) * c.ease))
+		}
+		c.repetitions++
+	} else {
+		c.repetitions = 0
+		c.interval = 1
+	}
+
+	miss := float64(maxRating - rating)
+	c.ease += 0.1 - miss*(0.08+miss*0.02)
+	if c.ease < minEase {
+		c.ease = minEase
+	}
+}
+
+// dueReview picks the most overdue question for review, if there is one.
+// Reviews take turns with new questions so they never crowd them out.
+func dueReview(sessions []SoloSession, now time.Time) (*reviewCard, bool) {
+	if len(sessions) == 0 || sessions[len(sessions)-1].Review {
+		return nil, false
+	}
+
+	var due *reviewCard
+	for _, card := range reviewSchedule(sessions) {
+		if card.due.After(now) {
+			continue
+		}
+		if due == nil || card.due.Before(due.due) || (card.due.Equal(due.due) && card.question < due.question) {
+			due = card
+		}
+	}
+	return due, due != nil
+}
+
+func fmtReviewMessage(question *Question, card *reviewCard, now time.Time) string {
+	var builder strings.Builder
+	builder.WriteString("Hey there! Today's question is a review :repeat:\n")
+	var lastTime string
+	switch {
+	case card.lastRating != 0:
+		lastTime = fmt.Sprintf("You rated it a %d/%d", card.lastRating, maxRating)
+	case card.lastOutcome == outcomeGaveUp:
+		lastTime = "You gave up on it"
+	default:
+		lastTime = "You solved it"
+	}
+	builder.WriteString(fmt.Sprintf("%s when you saw it %s ago, so it's time to see how well it stuck.\n\n", lastTime, fmtDaysAgo(card.lastSeen, now)))
+	builder.WriteString(fmt.Sprintf("[Today's Review](%s)\n\n", question.URL))
+	builder.WriteString(rateReminder)
+	return builder.String()
+}
+
+// rateReminder ends every solo message so that attempts get rated
+const rateReminder = "Once you've given it a go, let me know with `done` or `gaveup` and `rate` it from 1 (couldn't solve it) to 5 (easy). I'll bring it back for review at the right time."
+
+func fmtDaysAgo(then time.Time, now time.Time) string {
+	days := int(now.Sub(then).Hours() / 24)
+	if days < 1 {
+		return "less than a day"
+	}
+	return pluralize(days, "day")
+}
@@ -0,0 +1,298 @@
+package bot
+
+import (
+	"sort"
+)
+
+// This file implements Robert W. Irving's algorithm for the stable roommates
+// problem ("An Efficient Algorithm for the 'Stable Roommates' Problem", 1985),
+// in the variant that allows incomplete preference lists since not every pair
+// of Recursers is a valid match. It's the "roommates" matcher.
+
+// preferenceScore is how much recurser would like partner as their interviewer.
+// Experience matters most: the same level is best, someone more experienced is
+// next best and someone less experienced is least preferred. After that come
+// overlapping pairing difficulties and a shared interview environment.
+func preferenceScore(recurser Recurser, partner Recurser) int {
+	score := 0
+
+	switch diff := difficultyLevels[partner.Config.Experience] - difficultyLevels[recurser.Config.Experience]; {
+	case diff == 0:
+		score += 6
+	case diff > 0:
+		score += 5 - diff
+	default:
+		score += 2 + diff
+	}
+
+	for _, difficulty := range recurser.Config.PairingDifficulty {
+		if contains(partner.Config.PairingDifficulty, difficulty) {
+			score += 2
+		}
+	}
+
+	if recurser.Config.Environment == partner.Config.Environment {
+		score++
+	}
+
+	return score
+}
+
+// buildPreferences ranks everyone each recurser can pair with from most to
+// least preferred. Ties keep the order of recursers, which is shuffled
+// beforehand, so they're broken at random.
+func buildPreferences(recursers []Recurser, history matchHistory) [][]int {
+	prefs := make([][]int, len(recursers))
+
+	for i, recurser := range recursers {
+		candidates := make([]int, 0, len(recursers)-1)
+		scores := make(map[int]int)
+		for j, partner := range recursers {
+			if i != j && canPair(recurser, partner, history) {
+				candidates = append(candidates, j)
+				scores[j] = preferenceScore(recurser, partner)
+			}
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return scores[candidates[a]] > scores[candidates[b]]
+		})
+		prefs[i] = candidates
+	}
+
+	return prefs
+}
+
+// stableMatching pairs up as many recursers as it can with stable matchings.
+// When no stable matching exists for the whole pool, whoever can't be placed
+// sits out so the rest can still be matched; anyone left over gets another
+// round amongst themselves.
+func stableMatching(recursers []Recurser, history matchHistory) ([][2]Recurser, []Recurser) {
+	var pairs [][2]Recurser
+	remaining := recursers
+
+	for len(remaining) >= 2 {
+		matched, unmatched := stableSubsetMatching(remaining, history)
+		if len(matched) == 0 {
+			break
+		}
+		pairs = append(pairs, matched...)
+		remaining = unmatched
+	}
+
+	return pairs, remaining
+}
+
+// stableSubsetMatching finds a stable matching for the largest subset of
+// recursers it can by removing one person each time Irving's algorithm fails
+func stableSubsetMatching(recursers []Recurser, history matchHistory) ([][2]Recurser, []Recurser) {
+	var sittingOut []Recurser
+	pool := recursers
+
+	for {
+		partner, culprit := stableRoommates(buildPreferences(pool, history))
+		if culprit == -1 {
+			var pairs [][2]Recurser
+			var unmatched []Recurser
+			for i, j := range partner {
+				switch {
+				case j == -1:
+					unmatched = append(unmatched, pool[i])
+				case i < j:
+					pairs = append(pairs, [2]Recurser{pool[i], pool[j]})
+				}
+			}
+			return pairs, append(unmatched, sittingOut...)
+		}
+
+		sittingOut = append(sittingOut, pool[culprit])
+		rest := make([]Recurser, 0, len(pool)-1)
+		rest = append(rest, pool[:culprit]...)
+		pool = append(rest, pool[culprit+1:]...)
+	}
+}
+
+// roommatesTable holds the preference lists as Irving's algorithm whittles
+// them down. Pairs are only ever removed, and always from both lists at once.
+type roommatesTable struct {
+	prefs   [][]int
+	rank    []map[int]int
+	removed []map[int]bool
+	head    []int
+	tail    []int
+}
+
+func newRoommatesTable(prefs [][]int) *roommatesTable {
+	n := len(prefs)
+	t := &roommatesTable{
+		prefs:   prefs,
+		rank:    make([]map[int]int, n),
+		removed: make([]map[int]bool, n),
+		head:    make([]int, n),
+		tail:    make([]int, n),
+	}
+
+	for i, list := range prefs {
+		t.rank[i] = make(map[int]int, len(list))
+		t.removed[i] = make(map[int]bool)
+		for r, j := range list {
+			t.rank[i][j] = r
+		}
+		t.tail[i] = len(list) - 1
+	}
+
+	// only mutually acceptable pairs belong in the table
+	for i, list := range prefs {
+		for _, j := range list {
+			if _, ok := t.rank[j][i]; !ok {
+				t.remove(i, j)
+			}
+		}
+	}
+
+	return t
+}
+
+func (t *roommatesTable) remove(i int, j int) {
+	t.removed[i][j] = true
+	t.removed[j][i] = true
+}
+
+func (t *roommatesTable) first(i int) int {
+	for t.head[i] <= t.tail[i] && t.removed[i][t.prefs[i][t.head[i]]] {
+		t.head[i]++
+	}
+	if t.head[i] > t.tail[i] {
+		return -1
+	}
+	return t.prefs[i][t.head[i]]
+}
+
+func (t *roommatesTable) second(i int) int {
+	if t.first(i) == -1 {
+		return -1
+	}
+	for k := t.head[i] + 1; k <= t.tail[i]; k++ {
+		if j := t.prefs[i][k]; !t.removed[i][j] {
+			return j
+		}
+	}
+	return -1
+}
+
+func (t *roommatesTable) last(i int) int {
+	for t.tail[i] >= t.head[i] && t.removed[i][t.prefs[i][t.tail[i]]] {
+		t.tail[i]--
+	}
+	if t.tail[i] < t.head[i] {
+		return -1
+	}
+	return t.prefs[i][t.tail[i]]
+}
+
+func (t *roommatesTable) size(i int) int {
+	size := 0
+	for k := t.head[i]; k <= t.tail[i]; k++ {
+		if !t.removed[i][t.prefs[i][k]] {
+			size++
+		}
+	}
+	return size
+}
+
+// truncateAfter removes everyone i likes less than j from i's list
+func (t *roommatesTable) truncateAfter(i int, j int) {
+	for k := t.tail[i]; k > t.rank[i][j]; k-- {
+		t.remove(i, t.prefs[i][k])
+	}
+}
+
+// stableRoommates runs both phases of Irving's algorithm. It returns each
+// person's partner (-1 when unmatched) or, when no stable matching exists,
+// the person whose list ran dry.
+func stableRoommates(prefs [][]int) ([]int, int) {
+	n := len(prefs)
+	t := newRoommatesTable(prefs)
+
+	// Phase 1: everyone proposes down their list. Receiving a proposal means
+	// rejecting everyone worse than the proposer, which frees whoever was held
+	// before. People rejected by everyone simply go unmatched.
+	holding := make([]int, n)
+	free := make([]int, n)
+	for i := range free {
+		holding[i] = -1
+		free[i] = n - 1 - i
+	}
+	for len(free) > 0 {
+		x := free[len(free)-1]
+		free = free[:len(free)-1]
+
+		y := t.first(x)
+		if y == -1 {
+			continue
+		}
+		if held := holding[y]; held != -1 {
+			free = append(free, held)
+		}
+		holding[y] = x
+		t.truncateAfter(y, x)
+	}
+
+	inTable := make([]bool, n)
+	for i := range inTable {
+		inTable[i] = t.first(i) != -1
+	}
+
+	// Phase 2: as long as someone has a choice left, find a rotation and
+	// eliminate it. Running out of options at this point means there is no
+	// stable matching at all.
+	for {
+		p := -1
+		for i := 0; i < n; i++ {
+			if t.size(i) >= 2 {
+				p = i
+				break
+			}
+		}
+		if p == -1 {
+			break
+		}
+
+		seen := make(map[int]int)
+		var ps []int
+		for {
+			if start, ok := seen[p]; ok {
+				ps = ps[start:]
+				break
+			}
+			seen[p] = len(ps)
+			ps = append(ps, p)
+
+			q := t.second(p)
+			if q == -1 {
+				return nil, p
+			}
+			p = t.last(q)
+		}
+
+		qs := make([]int, len(ps))
+		for i, x := range ps {
+			qs[i] = t.second(x)
+		}
+		for i, x := range ps {
+			t.truncateAfter(qs[i], x)
+		}
+
+		for i := range inTable {
+			if inTable[i] && t.first(i) == -1 {
+				return nil, i
+			}
+		}
+	}
+
+	partner := make([]int, n)
+	for i := range partner {
+		partner[i] = t.first(i)
+	}
+	return partner, -1
+}
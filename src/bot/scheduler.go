@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// job is something Cron runs on a schedule. run gets the time it was
+// scheduled for, which is earlier than now when catching up.
+type job struct {
+	name     string
+	help     string
+	schedule jobSchedule
+	run      func(store Store, zulip ZulipSender, at time.Time, ctx context.Context)
+}
+
+// jobSchedule runs a job every so often, offset from midnight UTC, e.g. every
+// 24 hours offset by 9 hours is 9am UTC each day
+type jobSchedule struct {
+	every  time.Duration
+	offset time.Duration
+}
+
+var hourly = jobSchedule{every: time.Hour}
+
+func dailyAt(hour int) jobSchedule {
+	return jobSchedule{every: 24 * time.Hour, offset: time.Duration(hour) * time.Hour}
+}
+
+// slots are the times the job was scheduled for after one time and up to
+// and including another, oldest first
+func (s jobSchedule) slots(after time.Time, until time.Time) []time.Time {
+	slot := after.UTC().Truncate(s.every).Add(s.offset)
+	for !slot.After(after) {
+		slot = slot.Add(s.every)
+	}
+
+	var slots []time.Time
+	for ; !slot.After(until); slot = slot.Add(s.every) {
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+func (s jobSchedule) String() string {
+	if s == hourly {
+		return "every hour"
+	}
+	return fmt.Sprintf("every day at %s UTC", time.Time{}.Add(s.offset).Format(timeLayout))
+}
+
+// jobs are everything Cron runs, in the order it runs them
+var jobs = []*job{
+	{
+		name:     "pairs",
+		help:     "matches everyone in the pairing queue and messages them their partners",
+		schedule: dailyAt(9),
+		run:      MessagePairs,
+	},
+	{
+		name:     "solo",
+		help:     "sends a solo question to everyone whose hour it is",
+		schedule: hourly,
+		run:      MessageSolo,
+	},
+	{
+		name:     "daily",
+		help:     "posts the daily question to #**Daily LeetCode**",
+		schedule: dailyAt(13),
+		run:      PostDaily,
+	},
+}
+
+// maxCatchUp is how far back runs that were missed are made up for
+const maxCatchUp = 24 * time.Hour
+
+func jobNames() []string {
+	var names []string
+	for _, j := range jobs {
+		names = append(names, j.name)
+	}
+	return names
+}
+
+func findJob(name string) *job {
+	for _, j := range jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// runDueJobs runs every job that was scheduled since it last succeeded, once
+// per missed run. A job that fails is retried on the next tick.
+func runDueJobs(store Store, zulip ZulipSender, now time.Time, ctx context.Context) {
+	for _, j := range jobs {
+		last, err := store.LastJobRun(ctx, j.name)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		// a job that has never run starts from an hour ago, which is saved
+		// so that it's retried from there if this run fails. It's kept to
+		// the second, since Firestore drops nanoseconds and later claims
+		// compare against it.
+		if last.IsZero() {
+			start := now.Add(-time.Hour).Truncate(time.Second)
+			if ok, err := store.RecordJobRun(ctx, j.name, last, start); err != nil || !ok {
+				if err != nil {
+					log.Println(err)
+				}
+				continue
+			}
+			last = start
+		}
+		after := last
+		if now.Sub(last) > maxCatchUp {
+			after = now.Add(-maxCatchUp)
+		}
+
+		for _, slot := range j.schedule.slots(after, now) {
+			// claim the slot before running it, so that an overlapping tick
+			// that read the same last run leaves it alone
+			ok, err := store.RecordJobRun(ctx, j.name, last, slot)
+			if err != nil {
+				log.Println(err)
+				break
+			}
+			if !ok {
+				log.Println(fmt.Sprintf("The %s job for %s was already claimed", j.name, slot.Format(time.RFC3339)))
+				break
+			}
+			if err := runJob(j, store, zulip, slot, ctx); err != nil {
+				log.Println(err)
+				// hand the slot back so that the next tick retries it
+				if _, err := store.RecordJobRun(ctx, j.name, slot, last); err != nil {
+					log.Println(err)
+				}
+				break
+			}
+			log.Println(fmt.Sprintf("The %s job ran for %s", j.name, slot.Format(time.RFC3339)))
+			last = slot
+		}
+	}
+}
+
+// runJob runs the job, turning the panics jobs use for fatal errors into
+// an error
+func runJob(j *job, store Store, zulip ZulipSender, at time.Time, ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the %s job failed: %v", j.name, r)
+		}
+	}()
+	j.run(store, zulip, at, ctx)
+	return nil
+}
+
+// runJobNow is an admin's manual trigger. It counts as the job's latest
+// scheduled run, so a run that failed isn't retried on top of it.
+func runJobNow(name string, store Store, zulip ZulipSender, now time.Time, ctx context.Context) string {
+	j := findJob(name)
+	if j == nil {
+		return fmt.Sprintf("There's no %s job.", name)
+	}
+
+	if err := runJob(j, store, zulip, now, ctx); err != nil {
+		log.Println(err)
+		return fmt.Sprintf("The %s job failed: %v", name, err)
+	}
+
+	last, err := store.LastJobRun(ctx, name)
+	if err != nil {
+		log.Println(err)
+	}
+	if slots := j.schedule.slots(now.Add(-j.schedule.every), now); err == nil && len(slots) > 0 && slots[0].After(last) {
+		if _, err := store.RecordJobRun(ctx, name, last, slots[0]); err != nil {
+			log.Println(err)
+		}
+	}
+	return fmt.Sprintf("The %s job ran!", name)
+}
+
+func fmtJobs(store Store, ctx context.Context) string {
+	var b strings.Builder
+	b.WriteString("**Scheduled jobs:**\n")
+	for _, j := range jobs {
+		lastRun := "never"
+		last, err := store.LastJobRun(ctx, j.name)
+		if err != nil {
+			log.Println(err)
+			lastRun = "unknown"
+		} else if !last.IsZero() {
+			lastRun = last.UTC().Format("Mon Jan 2 15:04 MST")
+		}
+		b.WriteString(fmt.Sprintf("* `%s` %s, %s (last ran: %s)\n", j.name, j.help, j.schedule, lastRun))
+	}
+	b.WriteString("\nUse `jobs run <job>` to run one now.")
+	return b.String()
+}
+
+// runKey names a run of a job in the ledger by the day it's for, e.g.
+// "solo:2026-10-19"
+func runKey(job string, date string) string {
+	return job + ":" + date
+}
+
+// claimDeliveries claims the run's delivery to every recipient or, if any of
+// them was already claimed, to none of them
+func claimDeliveries(store Store, run string, recipients []string, now time.Time, ctx context.Context) bool {
+	var claimed []string
+	for _, recipient := range recipients {
+		ok, err := store.ClaimDelivery(ctx, Delivery{Run: run, Recipient: recipient, TimeStamp: now})
+		if err != nil {
+			log.Println(err)
+		}
+		if !ok {
+			releaseDeliveries(store, run, claimed, ctx)
+			return false
+		}
+		claimed = append(claimed, recipient)
+	}
+	return true
+}
+
+func releaseDeliveries(store Store, run string, recipients []string, ctx context.Context) {
+	for _, recipient := range recipients {
+		if err := store.ReleaseDelivery(ctx, run, recipient); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// delivered reports whether the recipient is in the run's deliveries
+func delivered(deliveries []Delivery, recipient string) bool {
+	for _, delivery := range deliveries {
+		if delivery.Recipient == recipient {
+			return true
+		}
+	}
+	return false
+}
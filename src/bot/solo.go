@@ -3,79 +3,97 @@ package bot
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"net/url"
+	"sort"
 	"strings"
 	"time"
-
-	"cloud.google.com/go/firestore"
-	"github.com/fatih/structs"
 )
 
-func MessageSolo(client *firestore.Client, ctx context.Context) {
-	today := strings.ToLower(time.Now().Weekday().String())[:3]
-
-	iter := client.Collection("recursers").
-		Where("isSkippingTomorrow", "==", false).
-		Where("config.soloDays", "array-contains", today).
-		Documents(ctx)
-
-	recursersList := iterToRecurserList(iter)
-
-	// if for some reason there's no matches today, we're done
-	if len(recursersList) == 0 {
-		log.Println("No one was signed up for solo sessions today")
-		return
+// MessageSolo sends a question to everyone whose solo question is due this
+// hour, in their own time zone. It runs every hour.
+func MessageSolo(store Store, zulip ZulipSender, now time.Time, ctx context.Context) {
+	recursersList, err := soloRecipientsDue(store, now, ctx)
+	if err != nil {
+		log.Panic(err)
 	}
 
-	// message the peeps!
-	doc, err := client.Collection("auth").Doc("api").Get(ctx)
+	// anyone who asked to skip the question they were due this hour has now
+	// skipped it, which the ledger records so a retry doesn't send it anyway
+	skippersList, err := store.Skippers(ctx)
 	if err != nil {
-		log.Panic(err)
+		log.Println(err)
+	}
+	for i := range skippersList {
+		if !soloDue(skippersList[i], now) {
+			continue
+		}
+		claimDeliveries(store, soloRun(skippersList[i], now), []string{skippersList[i].Id}, now, ctx)
+		skipping := false
+		err := store.UpdateRecurser(ctx, skippersList[i].Id, RecurserUpdate{IsSkippingTomorrow: &skipping})
+		if err != nil {
+			log.Println(err)
+		}
 	}
 
-	apikey := doc.Data()["key"]
-	botPassword := apikey.(string)
-	zulipClient := &http.Client{}
+	// if for some reason no one is due a question, we're done
+	if len(recursersList) == 0 {
+		log.Println("No one was due a solo session this hour")
+		return
+	}
 
+	// message the peeps! Anyone whose message fails is given back to a retry
+	var failed []string
 	for i := range recursersList {
 		interviewee := recursersList[i]
+		date := now.In(interviewee.Config.location()).Format(dateLayout)
 
-		messageRequest := url.Values{}
-		messageRequest.Add("type", "private")
-		messageRequest.Add("to", interviewee.Email)
-
-		question := selectQuestion(interviewee, client, ctx)
-		msg := fmtSoloMessage(question)
-		messageRequest.Add("content", msg)
+		// one question a day, however many times this runs
+		run := soloRun(interviewee, now)
+		if !claimDeliveries(store, run, []string{interviewee.Id}, now, ctx) {
+			log.Println(fmt.Sprintf("%s already got today's question", interviewee.Name))
+			continue
+		}
 
-		req, err := http.NewRequest("POST", zulipAPIURL, strings.NewReader(messageRequest.Encode()))
-		req.SetBasicAuth(botEmailAddress, botPassword)
-		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		if len(interviewee.SkipDates) > 0 && !takeSkipDate(store, interviewee, date, ctx) {
+			log.Println(fmt.Sprintf("%s skipped today", interviewee.Name))
+			continue
+		}
 
-		resp, err := zulipClient.Do(req)
-		if err != nil {
-			log.Panic(err)
+		// every so often a question they've rated comes back for review
+		question, card := selectReview(interviewee, store, now, ctx)
+		var msg string
+		if question != nil {
+			msg = fmtReviewMessage(question, card, now)
+		} else {
+			var repeat bool
+			question, repeat = selectQuestion(interviewee, false, store, now, ctx)
+			if question == nil {
+				log.Println(fmt.Sprintf("No question matched the config of %s", interviewee.Name))
+				releaseDeliveries(store, run, []string{interviewee.Id}, ctx)
+				continue
+			}
+			if repeat {
+				log.Println(fmt.Sprintf("%s has been sent every question matching their config", interviewee.Name))
+			}
+			msg = fmtSoloMessage(question, repeat)
 		}
-		defer resp.Body.Close()
 
-		respBodyText, err := ioutil.ReadAll(resp.Body)
+		_, err := zulip.SendPrivate([]string{interviewee.Email}, msg)
 		if err != nil {
 			log.Println(err)
-		} else {
-			log.Println(string(respBodyText))
-			log.Println(fmt.Sprintf("A question went out to %s", interviewee.Name))
+			releaseDeliveries(store, run, []string{interviewee.Id}, ctx)
+			failed = append(failed, interviewee.Name)
+			continue
 		}
+		log.Println(fmt.Sprintf("A question went out to %s", interviewee.Name))
 
-		session := map[string]interface{}{
-			"question":  question["id"],
-			"timeStamp": time.Now(),
+		session := SoloSession{
+			Question:  question.Id,
+			TimeStamp: now,
+			Review:    card != nil,
 		}
 
-		doc := client.Collection("soloSessions").Doc(interviewee.Id)
-		_, err = doc.Update(ctx, []firestore.Update{{Path: "sessions", Value: firestore.ArrayUnion(session)}})
+		err = store.AppendSoloSession(ctx, interviewee.Id, session)
 		if err != nil {
 			log.Println(err)
 		} else {
@@ -83,24 +101,110 @@ func MessageSolo(client *firestore.Client, ctx context.Context) {
 		}
 	}
 
-	// get everyone who was set to skip today and set them back to isSkippingTomorrow = false
-	iter = client.Collection("recursers").Where("isSkippingTomorrow", "==", true).Documents(ctx)
+	if len(failed) > 0 {
+		log.Panicf("Solo questions didn't go out to %s", strings.Join(failed, ", "))
+	}
+}
 
-	skippersList := iterToRecurserList(iter)
-	for i := range skippersList {
-		skippersList[i].IsSkippingTomorrow = false
-		_, err := client.Collection("recursers").Doc(skippersList[i].Id).Set(ctx, structs.Map(skippersList[i]), firestore.MergeAll)
+// soloRun is the ledger's run for the recurser's solo question on their
+// local date
+func soloRun(recurser Recurser, now time.Time) string {
+	return runKey("solo", now.In(recurser.Config.location()).Format(dateLayout))
+}
+
+// soloDue reports whether it's the recurser's solo hour on one of their solo
+// days, in their time zone
+func soloDue(recurser Recurser, now time.Time) bool {
+	local := now.In(recurser.Config.location())
+	return local.Hour() == recurser.Config.soloHour() && contains(recurser.Config.SoloDays, weekday(local))
+}
+
+// soloRecipientsDue is everyone who isn't skipping or paused and is due a
+// question now.
+// Time zones run from UTC-12 to UTC+14, so it's one of up to three days
+// somewhere in the world.
+func soloRecipientsDue(store Store, now time.Time, ctx context.Context) ([]Recurser, error) {
+	var days []string
+	for _, offset := range []time.Duration{-12 * time.Hour, 0, 14 * time.Hour} {
+		if day := weekday(now.UTC().Add(offset)); !contains(days, day) {
+			days = append(days, day)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var due []Recurser
+	for _, day := range days {
+		recursers, err := store.SoloRecipients(ctx, day)
 		if err != nil {
-			log.Println(err)
+			return nil, err
+		}
+		for _, recurser := range recursers {
+			if !seen[recurser.Id] && soloDue(recurser, now) && !recurser.isPaused(now, false) {
+				seen[recurser.Id] = true
+				due = append(due, recurser)
+			}
 		}
 	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].Id < due[j].Id
+	})
+	return due, nil
 }
 
-func fmtSoloMessage(question map[string]interface{}) string {
+// weekday is the day as it's written in SoloDays, e.g. "mon"
+func weekday(t time.Time) string {
+	return dayOptions[t.Weekday()]
+}
+
+// selectReview returns the question the recurser is due to review along with
+// its review card, or nil if nothing is due today
+func selectReview(recurser Recurser, store Store, now time.Time, ctx context.Context) (*Question, *reviewCard) {
+	sessions, err := store.SoloSessions(ctx, recurser.Id)
+	if err != nil {
+		log.Println(err)
+		return nil, nil
+	}
+	card, ok := dueReview(sessions, now)
+	if !ok {
+		return nil, nil
+	}
+
+	questions, err := store.Questions(ctx, QuestionQuery{Id: card.question})
+	if err != nil || len(questions) == 0 {
+		log.Println(fmt.Sprintf("Couldn't find question %d for %s to review: %v", card.question, recurser.Name, err))
+		return nil, nil
+	}
+	return &questions[0], card
+}
+
+// takeSkipDate forgets any of the recurser's skip dates up to and including
+// today and returns whether they still want today's question
+func takeSkipDate(store Store, recurser Recurser, date string, ctx context.Context) bool {
+	skipping := contains(recurser.SkipDates, date)
+
+	remaining := []string{}
+	for _, skipDate := range recurser.SkipDates {
+		if skipDate > date {
+			remaining = append(remaining, skipDate)
+		}
+	}
+	err := store.UpdateRecurser(ctx, recurser.Id, RecurserUpdate{SkipDates: &remaining})
+	if err != nil {
+		log.Println(err)
+	}
+	return !skipping
+}
+
+func fmtSoloMessage(question *Question, repeat bool) string {
 	var builder strings.Builder
 	builder.WriteString("Hey there! I've got your next question prepared and ready to go!\n")
 	builder.WriteString("The question was randomly selected based on your config and question history; use `config` to make modifications.\n\n")
-	builder.WriteString(fmt.Sprintf("[Today's Question](%s)\n\n", question["url"]))
-	builder.WriteString("Want even more practice? Feel free to `schedule` a mock interview or work on the daily question in #**Daily LeetCode** :)")
+	if repeat {
+		builder.WriteString("You've already had every question that fits your config, so here's the one you saw longest ago. Try `set topics` or `set difficulty` for something new!\n\n")
+	}
+	builder.WriteString(fmt.Sprintf("[Today's Question](%s)\n\n", question.URL))
+	builder.WriteString("Want even more practice? Feel free to `schedule` a mock interview or work on the daily question in #**Daily LeetCode** :)\n\n")
+	builder.WriteString(rateReminder)
 	return builder.String()
 }
@@ -0,0 +1,834 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations are applied in order and exactly once; the number of the
+// last one applied is kept in schema_migrations. Never edit a migration that
+// has shipped, append a new one instead.
+var sqliteMigrations = []string{
+	// 1: initial schema
+	`
+	CREATE TABLE recursers (
+		id                   TEXT PRIMARY KEY,
+		name                 TEXT NOT NULL,
+		email                TEXT NOT NULL,
+		is_skipping_tomorrow INTEGER NOT NULL DEFAULT 0,
+		is_pairing_tomorrow  INTEGER NOT NULL DEFAULT 0
+	);
+
+	-- list columns hold JSON arrays of strings, e.g. ["mon","tue"]
+	CREATE TABLE configs (
+		recurser_id        TEXT PRIMARY KEY REFERENCES recursers (id) ON DELETE CASCADE,
+		comments           TEXT NOT NULL DEFAULT '',
+		environment        TEXT NOT NULL DEFAULT '',
+		experience         TEXT NOT NULL DEFAULT '',
+		problem_set        TEXT NOT NULL DEFAULT '',
+		topics             TEXT NOT NULL DEFAULT '[]',
+		solo_days          TEXT NOT NULL DEFAULT '[]',
+		solo_difficulty    TEXT NOT NULL DEFAULT '[]',
+		pairing_difficulty TEXT NOT NULL DEFAULT '[]',
+		manual_question    INTEGER NOT NULL DEFAULT 0
+	);
+
+	-- a row here plays the part of the soloSessions/pairingSessions documents
+	CREATE TABLE session_histories (
+		recurser_id TEXT PRIMARY KEY
+	);
+
+	CREATE TABLE solo_sessions (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		recurser_id TEXT NOT NULL REFERENCES session_histories (recurser_id) ON DELETE CASCADE,
+		question    INTEGER NOT NULL,
+		time_stamp  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX solo_sessions_recurser ON solo_sessions (recurser_id);
+
+	CREATE TABLE pairing_sessions (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		recurser_id TEXT NOT NULL REFERENCES session_histories (recurser_id) ON DELETE CASCADE,
+		interviewer TEXT NOT NULL,
+		interviewee TEXT NOT NULL,
+		question    INTEGER NOT NULL,
+		time_stamp  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX pairing_sessions_recurser ON pairing_sessions (recurser_id);
+
+	CREATE TABLE questions (
+		id         INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		url        TEXT NOT NULL,
+		difficulty TEXT NOT NULL,
+		tags       TEXT NOT NULL DEFAULT '[]',
+		psets      TEXT NOT NULL DEFAULT '[]'
+	);
+	CREATE INDEX questions_difficulty ON questions (difficulty);
+
+	CREATE TABLE daily_questions (
+		date       TEXT PRIMARY KEY,
+		question   INTEGER NOT NULL,
+		time_stamp TIMESTAMP NOT NULL
+	);
+
+	-- holds "botToken" and "apiKey"
+	CREATE TABLE secrets (
+		name  TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`,
+	// 2: when each recurser joined the pairing queue
+	`
+	ALTER TABLE recursers ADD COLUMN queued_at TIMESTAMP;
+	`,
+	// 3: how many days in a row each recurser went unmatched
+	`
+	ALTER TABLE recursers ADD COLUMN unmatched_days INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 4: past partners each recurser asked to be paired with again
+	`
+	ALTER TABLE recursers ADD COLUMN rematch_with TEXT NOT NULL DEFAULT '[]';
+	`,
+	// 5: the interview order of three person sessions
+	`
+	ALTER TABLE pairing_sessions ADD COLUMN rotation TEXT NOT NULL DEFAULT '[]';
+	`,
+	// 6: days each recurser asked to skip
+	`
+	ALTER TABLE recursers ADD COLUMN skip_dates TEXT NOT NULL DEFAULT '[]';
+	`,
+	// 7: whether each recurser walks their problem set in order
+	`
+	ALTER TABLE configs ADD COLUMN sequential INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 8: spaced repetition reviews and how each attempt went
+	`
+	ALTER TABLE solo_sessions ADD COLUMN review INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE solo_sessions ADD COLUMN rating INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 9: whether each attempt was solved and how long it took
+	`
+	ALTER TABLE solo_sessions ADD COLUMN outcome TEXT NOT NULL DEFAULT '';
+	ALTER TABLE solo_sessions ADD COLUMN minutes INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 10: whether each recurser's difficulty adapts to how they're doing
+	`
+	ALTER TABLE configs ADD COLUMN adaptive_difficulty INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 11: whether each recurser's topics are weighted by weakness
+	`
+	ALTER TABLE configs ADD COLUMN weak_topics INTEGER NOT NULL DEFAULT 0;
+	`,
+	// 12: when each recurser gets their solo question, in their own time zone
+	`
+	ALTER TABLE configs ADD COLUMN solo_time TEXT NOT NULL DEFAULT '';
+	ALTER TABLE configs ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+	`,
+	// 13: when each scheduled job last ran
+	`
+	CREATE TABLE job_runs (
+		job      TEXT PRIMARY KEY,
+		last_run TIMESTAMP NOT NULL
+	);
+	`,
+	// 14: the run ledger, one row per recipient of each run of a job
+	`
+	CREATE TABLE deliveries (
+		run        TEXT NOT NULL,
+		recipient  TEXT NOT NULL,
+		time_stamp TIMESTAMP NOT NULL,
+		PRIMARY KEY (run, recipient)
+	);
+	`,
+	// 15: date ranges each recurser is paused for
+	`
+	ALTER TABLE recursers ADD COLUMN pauses TEXT NOT NULL DEFAULT '[]';
+	`,
+	// 16: the order each problem set lists its questions in
+	`
+	CREATE TABLE problem_sets (
+		name      TEXT PRIMARY KEY,
+		questions TEXT NOT NULL DEFAULT '[]'
+	);
+	`,
+}
+
+// SQLiteStore keeps everything in a single SQLite file, which is all a
+// self-hosted AlgoBot needs
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the database at path and brings its
+// schema up to date
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only has one writer anyway and this keeps ":memory:" databases
+	// from being split across connections
+	db.SetMaxOpenConns(1)
+
+	if err = migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// LoadFixtures seeds the database from the same JSON format MemoryStore uses;
+// it's the easiest way to get questions and secrets into a fresh install
+func (s *SQLiteStore) LoadFixtures(r io.Reader) error {
+	var fixtures Fixtures
+	if err := json.NewDecoder(r).Decode(&fixtures); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, recurser := range fixtures.Recursers {
+		if err := s.PutRecurser(ctx, recurser); err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(`INSERT OR IGNORE INTO session_histories (recurser_id) VALUES (?)`, recurser.Id); err != nil {
+			return err
+		}
+	}
+	for _, question := range fixtures.Questions {
+		if err := s.PutQuestion(ctx, question); err != nil {
+			return err
+		}
+	}
+	for name, ids := range fixtures.ProblemSets {
+		if err := s.PutProblemSet(ctx, name, ids); err != nil {
+			return err
+		}
+	}
+	for id, sessions := range fixtures.SoloSessions {
+		for _, session := range sessions {
+			if err := s.AppendSoloSession(ctx, id, session); err != nil {
+				return err
+			}
+		}
+	}
+	for id, sessions := range fixtures.PairingSessions {
+		for _, session := range sessions {
+			if err := s.AppendPairingSession(ctx, id, session); err != nil {
+				return err
+			}
+		}
+	}
+	if fixtures.BotToken != "" {
+		if err := s.putSecret("botToken", fixtures.BotToken); err != nil {
+			return err
+		}
+	}
+	if fixtures.APIKey != "" {
+		if err := s.putSecret("apiKey", fixtures.APIKey); err != nil {
+			return err
+		}
+	}
+	if fixtures.LinkSecret != "" {
+		if err := s.putSecret("linkSecret", fixtures.LinkSecret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const recurserColumns = `
+	r.id, r.name, r.email, r.is_skipping_tomorrow, r.is_pairing_tomorrow, r.queued_at, r.unmatched_days, r.rematch_with, r.skip_dates, r.pauses,
+	c.comments, c.environment, c.experience, c.problem_set, c.sequential, c.topics, c.weak_topics,
+	c.solo_days, c.solo_time, c.timezone, c.solo_difficulty, c.adaptive_difficulty, c.pairing_difficulty, c.manual_question`
+
+const recurserTables = `recursers r JOIN configs c ON c.recurser_id = r.id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecurser(row rowScanner) (Recurser, error) {
+	var recurser Recurser
+	var queuedAt sql.NullTime
+	var rematchWith, skipDates, pauses, topics, soloDays, soloDifficulty, pairingDifficulty string
+
+	err := row.Scan(
+		&recurser.Id, &recurser.Name, &recurser.Email, &recurser.IsSkippingTomorrow, &recurser.IsPairingTomorrow, &queuedAt, &recurser.UnmatchedDays, &rematchWith, &skipDates, &pauses,
+		&recurser.Config.Comments, &recurser.Config.Environment, &recurser.Config.Experience, &recurser.Config.ProblemSet, &recurser.Config.Sequential, &topics, &recurser.Config.WeakTopics,
+		&soloDays, &recurser.Config.SoloTime, &recurser.Config.Timezone, &soloDifficulty, &recurser.Config.AdaptiveDifficulty, &pairingDifficulty, &recurser.Config.ManualQuestion,
+	)
+	if err != nil {
+		return recurser, err
+	}
+
+	recurser.QueuedAt = queuedAt.Time
+	recurser.RematchWith = decodeList(rematchWith)
+	recurser.SkipDates = decodeList(skipDates)
+	recurser.Pauses = decodePauses(pauses)
+	recurser.Config.Topics = decodeList(topics)
+	recurser.Config.SoloDays = decodeList(soloDays)
+	recurser.Config.SoloDifficulty = decodeList(soloDifficulty)
+	recurser.Config.PairingDifficulty = decodeList(pairingDifficulty)
+	return recurser, nil
+}
+
+func (s *SQLiteStore) queryRecursers(ctx context.Context, where string, args ...interface{}) ([]Recurser, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+recurserColumns+` FROM `+recurserTables+` WHERE `+where+` ORDER BY r.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recursersList []Recurser
+	for rows.Next() {
+		recurser, err := scanRecurser(rows)
+		if err != nil {
+			return nil, err
+		}
+		recursersList = append(recursersList, recurser)
+	}
+	return recursersList, rows.Err()
+}
+
+func (s *SQLiteStore) GetRecurser(ctx context.Context, id string) (Recurser, bool, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+recurserColumns+` FROM `+recurserTables+` WHERE r.id = ?`, id)
+	recurser, err := scanRecurser(row)
+	if err == sql.ErrNoRows {
+		return Recurser{}, false, nil
+	}
+	if err != nil {
+		return Recurser{}, false, err
+	}
+	return recurser, true, nil
+}
+
+func (s *SQLiteStore) PutRecurser(ctx context.Context, recurser Recurser) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO recursers (id, name, email, is_skipping_tomorrow, is_pairing_tomorrow, queued_at, unmatched_days, rematch_with, skip_dates, pauses)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			email = excluded.email,
+			is_skipping_tomorrow = excluded.is_skipping_tomorrow,
+			is_pairing_tomorrow = excluded.is_pairing_tomorrow,
+			queued_at = excluded.queued_at,
+			unmatched_days = excluded.unmatched_days,
+			rematch_with = excluded.rematch_with,
+			skip_dates = excluded.skip_dates,
+			pauses = excluded.pauses`,
+		recurser.Id, recurser.Name, recurser.Email, recurser.IsSkippingTomorrow, recurser.IsPairingTomorrow,
+		nullTime(recurser.QueuedAt), recurser.UnmatchedDays, encodeList(recurser.RematchWith), encodeList(recurser.SkipDates), encodePauses(recurser.Pauses),
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = putConfig(ctx, tx, recurser.Id, recurser.Config); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func putConfig(ctx context.Context, tx *sql.Tx, id string, config UserConfig) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO configs (recurser_id, comments, environment, experience, problem_set, sequential, topics, weak_topics,
+			solo_days, solo_time, timezone, solo_difficulty, adaptive_difficulty, pairing_difficulty, manual_question)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (recurser_id) DO UPDATE SET
+			comments = excluded.comments,
+			environment = excluded.environment,
+			experience = excluded.experience,
+			problem_set = excluded.problem_set,
+			sequential = excluded.sequential,
+			topics = excluded.topics,
+			weak_topics = excluded.weak_topics,
+			solo_days = excluded.solo_days,
+			solo_time = excluded.solo_time,
+			timezone = excluded.timezone,
+			solo_difficulty = excluded.solo_difficulty,
+			adaptive_difficulty = excluded.adaptive_difficulty,
+			pairing_difficulty = excluded.pairing_difficulty,
+			manual_question = excluded.manual_question`,
+		id, config.Comments, config.Environment, config.Experience, config.ProblemSet, config.Sequential, encodeList(config.Topics), config.WeakTopics,
+		encodeList(config.SoloDays), config.SoloTime, config.Timezone, encodeList(config.SoloDifficulty), config.AdaptiveDifficulty, encodeList(config.PairingDifficulty), config.ManualQuestion,
+	)
+	return err
+}
+
+func (s *SQLiteStore) DeleteRecurser(ctx context.Context, id string) error {
+	// configs go with it thanks to ON DELETE CASCADE
+	_, err := s.db.ExecContext(ctx, `DELETE FROM recursers WHERE id = ?`, id)
+	return err
+}
+
+func (s *SQLiteStore) UpdateRecurser(ctx context.Context, id string, update RecurserUpdate) error {
+	var columns []string
+	var args []interface{}
+	if update.IsSkippingTomorrow != nil {
+		columns = append(columns, "is_skipping_tomorrow = ?")
+		args = append(args, *update.IsSkippingTomorrow)
+	}
+	if update.IsPairingTomorrow != nil {
+		columns = append(columns, "is_pairing_tomorrow = ?")
+		args = append(args, *update.IsPairingTomorrow)
+	}
+	if update.QueuedAt != nil {
+		columns = append(columns, "queued_at = ?")
+		args = append(args, nullTime(*update.QueuedAt))
+	}
+	if update.UnmatchedDays != nil {
+		columns = append(columns, "unmatched_days = ?")
+		args = append(args, *update.UnmatchedDays)
+	}
+	if update.RematchWith != nil {
+		columns = append(columns, "rematch_with = ?")
+		args = append(args, encodeList(*update.RematchWith))
+	}
+	if update.SkipDates != nil {
+		columns = append(columns, "skip_dates = ?")
+		args = append(args, encodeList(*update.SkipDates))
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	res, err := s.db.ExecContext(ctx, `UPDATE recursers SET `+strings.Join(columns, ", ")+` WHERE id = ?`, append(args, id)...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound("recursers", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateConfig(ctx context.Context, id string, config UserConfig) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM recursers WHERE id = ?)`, id).Scan(&exists)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !exists {
+		tx.Rollback()
+		return notFound("recursers", id)
+	}
+
+	if err = putConfig(ctx, tx, id, config); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) PairingQueue(ctx context.Context) ([]Recurser, error) {
+	return s.queryRecursers(ctx, `r.is_pairing_tomorrow`)
+}
+
+func (s *SQLiteStore) SoloRecipients(ctx context.Context, day string) ([]Recurser, error) {
+	return s.queryRecursers(ctx, `NOT r.is_skipping_tomorrow AND c.solo_days LIKE ? ESCAPE '\'`, listPattern(day))
+}
+
+func (s *SQLiteStore) Skippers(ctx context.Context) ([]Recurser, error) {
+	return s.queryRecursers(ctx, `r.is_skipping_tomorrow`)
+}
+
+func (s *SQLiteStore) CreateSessionHistory(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO session_histories (recurser_id) VALUES (?)`, id)
+	if isConstraintError(err) {
+		return alreadyExists("sessionHistories", id)
+	}
+	return err
+}
+
+func (s *SQLiteStore) DeleteSessionHistory(ctx context.Context, id string) error {
+	// the sessions themselves go with it thanks to ON DELETE CASCADE
+	_, err := s.db.ExecContext(ctx, `DELETE FROM session_histories WHERE recurser_id = ?`, id)
+	return err
+}
+
+func (s *SQLiteStore) AppendSoloSession(ctx context.Context, id string, session SoloSession) error {
+	// the NOT EXISTS mirrors firestore.ArrayUnion, which never stores the same element twice
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO solo_sessions (recurser_id, question, time_stamp, review, rating, outcome, minutes)
+		SELECT ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM solo_sessions
+			WHERE recurser_id = ? AND question = ? AND time_stamp = ? AND review = ? AND rating = ? AND outcome = ? AND minutes = ?
+		)`,
+		id, session.Question, session.TimeStamp, session.Review, session.Rating, session.Outcome, session.Minutes,
+		id, session.Question, session.TimeStamp, session.Review, session.Rating, session.Outcome, session.Minutes,
+	)
+	if isConstraintError(err) {
+		return notFound("soloSessions", id)
+	}
+	return err
+}
+
+func (s *SQLiteStore) UpdateLastSoloSession(ctx context.Context, id string, session SoloSession) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE solo_sessions SET question = ?, time_stamp = ?, review = ?, rating = ?, outcome = ?, minutes = ?
+		WHERE id = (SELECT MAX(id) FROM solo_sessions WHERE recurser_id = ?)`,
+		session.Question, session.TimeStamp, session.Review, session.Rating, session.Outcome, session.Minutes, id,
+	)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return notFound("soloSessions", id)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AppendPairingSession(ctx context.Context, id string, session PairingSession) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pairing_sessions (recurser_id, interviewer, interviewee, question, time_stamp, rotation)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM pairing_sessions
+			WHERE recurser_id = ? AND interviewer = ? AND interviewee = ? AND question = ? AND time_stamp = ? AND rotation = ?
+		)`,
+		id, session.Interviewer, session.Interviewee, session.Question, session.TimeStamp, encodeList(session.Rotation),
+		id, session.Interviewer, session.Interviewee, session.Question, session.TimeStamp, encodeList(session.Rotation),
+	)
+	if isConstraintError(err) {
+		return notFound("pairingSessions", id)
+	}
+	return err
+}
+
+func (s *SQLiteStore) SoloSessions(ctx context.Context, id string) ([]SoloSession, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT question, time_stamp, review, rating, outcome, minutes FROM solo_sessions
+		WHERE recurser_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []SoloSession
+	for rows.Next() {
+		var session SoloSession
+		if err = rows.Scan(&session.Question, &session.TimeStamp, &session.Review, &session.Rating, &session.Outcome, &session.Minutes); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *SQLiteStore) PairingSessions(ctx context.Context, id string) ([]PairingSession, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT interviewer, interviewee, question, time_stamp, rotation FROM pairing_sessions
+		WHERE recurser_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []PairingSession
+	for rows.Next() {
+		var session PairingSession
+		var rotation string
+		if err = rows.Scan(&session.Interviewer, &session.Interviewee, &session.Question, &session.TimeStamp, &rotation); err != nil {
+			return nil, err
+		}
+		if list := decodeList(rotation); len(list) > 0 {
+			session.Rotation = list
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// PutQuestion adds or replaces a question in the question bank
+func (s *SQLiteStore) PutQuestion(ctx context.Context, question Question) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO questions (id, name, url, difficulty, tags, psets)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		question.Id, question.Name, question.URL, question.Difficulty, encodeList(question.Tags), encodeList(question.Psets),
+	)
+	return err
+}
+
+// PutProblemSet adds or replaces the order a problem set lists its questions in
+func (s *SQLiteStore) PutProblemSet(ctx context.Context, name string, ids []int) error {
+	if ids == nil {
+		ids = []int{}
+	}
+	encoded, _ := json.Marshal(ids)
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO problem_sets (name, questions) VALUES (?, ?)`, name, string(encoded))
+	return err
+}
+
+func (s *SQLiteStore) ProblemSet(ctx context.Context, name string) ([]int, error) {
+	var encoded string
+	err := s.db.QueryRowContext(ctx, `SELECT questions FROM problem_sets WHERE name = ?`, name).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	err = json.Unmarshal([]byte(encoded), &ids)
+	return ids, err
+}
+
+func (s *SQLiteStore) Questions(ctx context.Context, query QuestionQuery) ([]Question, error) {
+	where := `1`
+	var args []interface{}
+	if query.Id != 0 {
+		where += ` AND id = ?`
+		args = append(args, query.Id)
+	}
+	if query.Difficulty != "" {
+		where += ` AND difficulty = ?`
+		args = append(args, query.Difficulty)
+	}
+	if query.Tag != "" {
+		where += ` AND tags LIKE ? ESCAPE '\'`
+		args = append(args, listPattern(query.Tag))
+	}
+	if query.ProblemSet != "" && query.ProblemSet != "random" {
+		where += ` AND psets LIKE ? ESCAPE '\'`
+		args = append(args, listPattern(query.ProblemSet))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, url, difficulty, tags, psets FROM questions WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var questions []Question
+	for rows.Next() {
+		var question Question
+		var tags, psets string
+		if err = rows.Scan(&question.Id, &question.Name, &question.URL, &question.Difficulty, &tags, &psets); err != nil {
+			return nil, err
+		}
+		question.Tags = decodeList(tags)
+		question.Psets = decodeList(psets)
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+
+func (s *SQLiteStore) CreateDailyQuestion(ctx context.Context, date string, daily DailyQuestion) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO daily_questions (date, question, time_stamp) VALUES (?, ?, ?)`,
+		date, daily.Question, daily.TimeStamp)
+	if isConstraintError(err) {
+		return alreadyExists("dailyQuestions", date)
+	}
+	return err
+}
+
+func (s *SQLiteStore) LastJobRun(ctx context.Context, job string) (time.Time, error) {
+	var lastRun time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT last_run FROM job_runs WHERE job = ?`, job).Scan(&lastRun)
+	if err == sql.ErrNoRows {
+		return lastRun, nil
+	}
+	return lastRun, err
+}
+
+func (s *SQLiteStore) RecordJobRun(ctx context.Context, job string, last time.Time, at time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var current time.Time
+	err = tx.QueryRowContext(ctx, `SELECT last_run FROM job_runs WHERE job = ?`, job).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		tx.Rollback()
+		return false, err
+	}
+	if !current.Equal(last) {
+		tx.Rollback()
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO job_runs (job, last_run) VALUES (?, ?)
+		ON CONFLICT (job) DO UPDATE SET last_run = excluded.last_run`,
+		job, at)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *SQLiteStore) ClaimDelivery(ctx context.Context, delivery Delivery) (bool, error) {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO deliveries (run, recipient, time_stamp) VALUES (?, ?, ?)`,
+		delivery.Run, delivery.Recipient, delivery.TimeStamp)
+	if isConstraintError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SQLiteStore) ReleaseDelivery(ctx context.Context, run string, recipient string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM deliveries WHERE run = ? AND recipient = ?`, run, recipient)
+	return err
+}
+
+func (s *SQLiteStore) Deliveries(ctx context.Context, run string) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT run, recipient, time_stamp FROM deliveries WHERE run = ? ORDER BY time_stamp, recipient`, run)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var delivery Delivery
+		if err := rows.Scan(&delivery.Run, &delivery.Recipient, &delivery.TimeStamp); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SQLiteStore) BotToken(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "botToken")
+}
+
+func (s *SQLiteStore) APIKey(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "apiKey")
+}
+
+func (s *SQLiteStore) LinkSecret(ctx context.Context) (string, error) {
+	return s.readSecret(ctx, "linkSecret")
+}
+
+func (s *SQLiteStore) readSecret(ctx context.Context, name string) (string, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM secrets WHERE name = ?`, name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no %s has been set", name)
+	}
+	return value, err
+}
+
+func (s *SQLiteStore) putSecret(name string, value string) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO secrets (name, value) VALUES (?, ?)`, name, value)
+	return err
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func encodeList(list []string) string {
+	if list == nil {
+		list = []string{}
+	}
+	encoded, _ := json.Marshal(list)
+	return string(encoded)
+}
+
+func decodeList(encoded string) []string {
+	list := []string{}
+	json.Unmarshal([]byte(encoded), &list)
+	return list
+}
+
+func encodePauses(pauses []Pause) string {
+	if pauses == nil {
+		pauses = []Pause{}
+	}
+	encoded, _ := json.Marshal(pauses)
+	return string(encoded)
+}
+
+func decodePauses(encoded string) []Pause {
+	pauses := []Pause{}
+	json.Unmarshal([]byte(encoded), &pauses)
+	return pauses
+}
+
+// likeEscaper escapes LIKE wildcards for patterns with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// listPattern matches a JSON list column containing value (our version of
+// array-contains). Queries using it must add ESCAPE '\'.
+func listPattern(value string) string {
+	quoted, _ := json.Marshal(value)
+	return "%" + likeEscaper.Replace(string(quoted)) + "%"
+}
+
+func isConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint
+}
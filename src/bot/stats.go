@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// soloStats sums up how a recurser's solo sessions went
+type soloStats struct {
+	received      int
+	reviews       int
+	solved        int
+	gaveUp        int
+	timedSolves   int // solves that came with a time
+	solveMinutes  int // total time of the timed solves
+	currentStreak int // questions solved in a row up to now
+	longestStreak int
+	// solvedByDifficulty counts solves of questions we could look up
+	solvedByDifficulty map[string]int
+}
+
+// computeSoloStats goes through the sessions, oldest first. Any question
+// that wasn't solved breaks a streak, except the latest one, which they may
+// still be working on.
+func computeSoloStats(sessions []SoloSession, questions map[int]Question) soloStats {
+	stats := soloStats{solvedByDifficulty: make(map[string]int)}
+
+	streak := 0
+	for i, session := range sessions {
+		stats.received++
+		if session.Review {
+			stats.reviews++
+		}
+
+		switch {
+		case session.Outcome == outcomeSolved:
+			stats.solved++
+			if session.Minutes > 0 {
+				stats.timedSolves++
+				stats.solveMinutes += session.Minutes
+			}
+			if question, ok := questions[session.Question]; ok {
+				stats.solvedByDifficulty[question.Difficulty]++
+			}
+			streak++
+			if streak > stats.longestStreak {
+				stats.longestStreak = streak
+			}
+		case session.Outcome == outcomeGaveUp:
+			stats.gaveUp++
+			streak = 0
+		case i < len(sessions)-1:
+			streak = 0
+		}
+	}
+	stats.currentStreak = streak
+
+	return stats
+}
+
+func fmtSoloStats(stats soloStats) string {
+	if stats.received == 0 {
+		return "You haven't gotten a question from me yet, so there's nothing to show!"
+	}
+
+	var b strings.Builder
+	b.WriteString("**Your solo sessions so far:**\n")
+	b.WriteString(fmt.Sprintf("* Questions received: %d", stats.received))
+	if stats.reviews > 0 {
+		b.WriteString(fmt.Sprintf(" (%s)", pluralize(stats.reviews, "review")))
+	}
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("* Solved: %d, gave up: %d, unreported: %d\n", stats.solved, stats.gaveUp, stats.received-stats.solved-stats.gaveUp))
+
+	if stats.solved > 0 {
+		var byDifficulty []string
+		for _, difficulty := range difficultyOptions {
+			if count := stats.solvedByDifficulty[difficulty]; count > 0 {
+				byDifficulty = append(byDifficulty, fmt.Sprintf("%d %s", count, difficulty))
+			}
+		}
+		if len(byDifficulty) > 0 {
+			b.WriteString(fmt.Sprintf("* Solved by difficulty: %s\n", strings.Join(byDifficulty, ", ")))
+		}
+	}
+	if stats.timedSolves > 0 {
+		b.WriteString(fmt.Sprintf("* Average time to solve: %s\n", pluralize(stats.solveMinutes/stats.timedSolves, "minute")))
+	}
+
+	b.WriteString(fmt.Sprintf("* Current streak: %s solved in a row (longest: %d)", pluralize(stats.currentStreak, "question"), stats.longestStreak))
+	if stats.currentStreak > 0 && stats.currentStreak == stats.longestStreak {
+		b.WriteString(" :fire:")
+	}
+	return b.String()
+}
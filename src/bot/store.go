@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"context"
+	"time"
+)
+
+// store is the backend every handler and cron job reads from and writes to.
+// It is set once at startup through SetStore.
+var store Store
+
+// SetStore swaps out the backend used by the bot
+func SetStore(s Store) {
+	store = s
+}
+
+// Store is everything AlgoBot needs from a database. Keeping the bot behind
+// this interface means the handlers don't care whether they're talking to
+// Firestore or something else entirely.
+type Store interface {
+	// GetRecurser returns the stored recurser and whether they exist at all
+	GetRecurser(ctx context.Context, id string) (Recurser, bool, error)
+	PutRecurser(ctx context.Context, recurser Recurser) error
+	DeleteRecurser(ctx context.Context, id string) error
+	UpdateConfig(ctx context.Context, id string, config UserConfig) error
+	// UpdateRecurser changes only the fields set in the update, so a job
+	// doesn't write back anything the user changed while it ran
+	UpdateRecurser(ctx context.Context, id string, update RecurserUpdate) error
+
+	// PairingQueue returns everyone waiting to be matched for a mock interview
+	PairingQueue(ctx context.Context) ([]Recurser, error)
+	// SoloRecipients returns everyone who wants a question on the given day
+	// (e.g. "mon") and is not skipping it
+	SoloRecipients(ctx context.Context, day string) ([]Recurser, error)
+	// Skippers returns everyone who asked to skip their next solo session
+	Skippers(ctx context.Context) ([]Recurser, error)
+
+	CreateSessionHistory(ctx context.Context, id string) error
+	DeleteSessionHistory(ctx context.Context, id string) error
+	AppendSoloSession(ctx context.Context, id string, session SoloSession) error
+	AppendPairingSession(ctx context.Context, id string, session PairingSession) error
+	// UpdateLastSoloSession replaces the user's most recent solo session
+	UpdateLastSoloSession(ctx context.Context, id string, session SoloSession) error
+	// SoloSessions and PairingSessions return a user's history, oldest first
+	SoloSessions(ctx context.Context, id string) ([]SoloSession, error)
+	PairingSessions(ctx context.Context, id string) ([]PairingSession, error)
+
+	Questions(ctx context.Context, query QuestionQuery) ([]Question, error)
+	// ProblemSet is the IDs of the problem set's questions in the order it
+	// lists them, or nil if that isn't known
+	ProblemSet(ctx context.Context, name string) ([]int, error)
+	// CreateDailyQuestion records the daily question; it fails if one was
+	// already recorded for that date
+	CreateDailyQuestion(ctx context.Context, date string, daily DailyQuestion) error
+
+	// LastJobRun is when the latest successful run of a scheduled job was
+	// scheduled for, or the zero time if it never ran
+	LastJobRun(ctx context.Context, job string) (time.Time, error)
+	// RecordJobRun moves a job's last run from last to at. It reports false
+	// if the last run is no longer last, so that overlapping ticks can claim
+	// a run before making it and only one of them makes it.
+	RecordJobRun(ctx context.Context, job string, last time.Time, at time.Time) (bool, error)
+
+	// ClaimDelivery records that a run of a job is delivering to a recipient.
+	// It reports false if someone already claimed it, so that a retried or
+	// overlapping run never delivers twice.
+	ClaimDelivery(ctx context.Context, delivery Delivery) (bool, error)
+	// ReleaseDelivery gives up a claim whose delivery failed so that it's
+	// tried again
+	ReleaseDelivery(ctx context.Context, run string, recipient string) error
+	// Deliveries returns every delivery claimed for the run
+	Deliveries(ctx context.Context, run string) ([]Delivery, error)
+
+	// BotToken is the token Zulip sends along with outgoing webhooks
+	BotToken(ctx context.Context) (string, error)
+	// APIKey is the key the bot uses to authenticate against the Zulip API
+	APIKey(ctx context.Context) (string, error)
+	// LinkSecret is the key the links to config and history pages are
+	// signed with, shared by every instance of the bot
+	LinkSecret(ctx context.Context) (string, error)
+
+	Close() error
+}
+
+// Question mirrors the documents written by scripts/main.py
+type Question struct {
+	Id         int      `firestore:"id"`
+	Name       string   `firestore:"name"`
+	URL        string   `firestore:"url"`
+	Difficulty string   `firestore:"difficulty"`
+	Tags       []string `firestore:"tags"`
+	Psets      []string `firestore:"psets"`
+}
+
+// QuestionQuery narrows down the question bank. Empty fields match anything,
+// as does a ProblemSet of "random".
+type QuestionQuery struct {
+	Id         int
+	Difficulty string
+	Tag        string
+	ProblemSet string
+}
+
+// SoloSession is one attempt at a question sent by MessageSolo
+type SoloSession struct {
+	Question  int       `firestore:"question"`
+	TimeStamp time.Time `firestore:"timeStamp"`
+	// Review is set when the question was resent for spaced repetition
+	Review bool `firestore:"review,omitempty"`
+	// Rating is how the attempt went, from 1 (couldn't solve it) to 5 (easy),
+	// or 0 if it wasn't rated
+	Rating int `firestore:"rating,omitempty"`
+	// Outcome is outcomeSolved, outcomeGaveUp or empty if we haven't heard
+	Outcome string `firestore:"outcome,omitempty"`
+	// Minutes is how long the attempt took, or 0 if they didn't say
+	Minutes int `firestore:"minutes,omitempty"`
+}
+
+// What came of a solo session, as reported with `done` and `gaveup`
+const (
+	outcomeSolved = "solved"
+	outcomeGaveUp = "gaveUp"
+)
+
+type PairingSession struct {
+	Interviewer string    `firestore:"interviewer"`
+	Interviewee string    `firestore:"interviewee"`
+	Question    int       `firestore:"question"`
+	TimeStamp   time.Time `firestore:"timeStamp"`
+	// Rotation lists the ids of a three person session in interview order,
+	// each interviewing the next and the last interviewing the first. It's
+	// empty for regular pairs.
+	Rotation []string `firestore:"rotation,omitempty"`
+}
+
+type DailyQuestion struct {
+	Question  int       `firestore:"question"`
+	TimeStamp time.Time `firestore:"timeStamp"`
+}
+
+// Delivery is one recipient's entry in the run ledger, e.g. Ada's solo
+// question on the run "solo:2026-10-19"
+type Delivery struct {
+	Run       string    `firestore:"run"`
+	Recipient string    `firestore:"recipient"`
+	TimeStamp time.Time `firestore:"timeStamp"`
+}
+
+// ProblemSet is the order a problem set lists its questions in
+type ProblemSet struct {
+	Questions []int `firestore:"questions"`
+}
+
+// RecurserUpdate is a change to some of a recurser's fields; nil ones are
+// left as they are
+type RecurserUpdate struct {
+	IsSkippingTomorrow *bool
+	IsPairingTomorrow  *bool
+	QueuedAt           *time.Time
+	UnmatchedDays      *int
+	RematchWith        *[]string
+	SkipDates          *[]string
+}
+
+func (u RecurserUpdate) apply(recurser *Recurser) {
+	if u.IsSkippingTomorrow != nil {
+		recurser.IsSkippingTomorrow = *u.IsSkippingTomorrow
+	}
+	if u.IsPairingTomorrow != nil {
+		recurser.IsPairingTomorrow = *u.IsPairingTomorrow
+	}
+	if u.QueuedAt != nil {
+		recurser.QueuedAt = *u.QueuedAt
+	}
+	if u.UnmatchedDays != nil {
+		recurser.UnmatchedDays = *u.UnmatchedDays
+	}
+	if u.RematchWith != nil {
+		recurser.RematchWith = *u.RematchWith
+	}
+	if u.SkipDates != nil {
+		recurser.SkipDates = *u.SkipDates
+	}
+}
+
+// JobRun records the latest successful run of a scheduled job
+type JobRun struct {
+	LastRun time.Time `firestore:"lastRun"`
+}
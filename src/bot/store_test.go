@@ -0,0 +1,453 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// tests run from src/bot rather than the repo root
+	botMessages = InitMessenger("messages.json")
+	SetZulip("https://zulip.example.com", "algo-bot@zulip.example.com")
+	SetServerURL("https://algobot.example.com")
+	os.Exit(m.Run())
+}
+
+// newTestStore seeds a fresh in-memory store from testdata and makes it the bot's store
+func newTestStore(t *testing.T) *MemoryStore {
+	t.Helper()
+
+	file, err := os.Open("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	s := NewMemoryStore()
+	if err = s.LoadFixtures(file); err != nil {
+		t.Fatal(err)
+	}
+	SetStore(s)
+	if err = LoadLinkSecret(s, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// testStores seeds every backend that runs without outside services with the same fixtures
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	sqlite, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	file, err := os.Open("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = sqlite.LoadFixtures(file); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Store{
+		"memory": newTestStore(t),
+		"sqlite": sqlite,
+	}
+}
+
+func soloSessionCount(t *testing.T, s Store, id string) int {
+	t.Helper()
+
+	sessions, err := s.SoloSessions(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(sessions)
+}
+
+func TestStoreQuestions(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			testStoreQuestions(t, s)
+		})
+	}
+}
+
+func testStoreQuestions(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	table := []struct {
+		query QuestionQuery
+		want  []int
+	}{
+		{
+			query: QuestionQuery{Difficulty: "easy"},
+			want:  []int{1, 26, 104},
+		},
+		{
+			query: QuestionQuery{Difficulty: "easy", ProblemSet: "top100Liked"},
+			want:  []int{1, 104},
+		},
+		{
+			query: QuestionQuery{Difficulty: "medium", ProblemSet: "random"},
+			want:  []int{3, 133},
+		},
+		{
+			query: QuestionQuery{Tag: "tree"},
+			want:  []int{104, 297},
+		},
+		{
+			query: QuestionQuery{Difficulty: "hard", Tag: "array", ProblemSet: "topInterview"},
+			want:  []int{4},
+		},
+		{
+			query: QuestionQuery{Difficulty: "medium", Tag: "graph", ProblemSet: "topInterview"},
+			want:  []int{},
+		},
+		// tags are matched as they are, not as patterns
+		{
+			query: QuestionQuery{Tag: "tre_"},
+			want:  []int{},
+		},
+		{
+			query: QuestionQuery{Tag: "%"},
+			want:  []int{},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		t.Run(name, func(t *testing.T) {
+			questions, err := s.Questions(ctx, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]int, 0)
+			for _, question := range questions {
+				got = append(got, question.Id)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+			}
+		})
+	}
+
+	if order, err := s.ProblemSet(ctx, "topInterview"); err != nil || fmt.Sprint(order) != "[1 26 3 104 297 4]" {
+		t.Errorf("Expected topInterview in the order it's listed, got %v (%v)", order, err)
+	}
+	if order, err := s.ProblemSet(ctx, "top100Liked"); err != nil || order != nil {
+		t.Errorf("Expected no order for top100Liked, got %v (%v)", order, err)
+	}
+}
+
+func TestStoreRecipients(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			testStoreRecipients(t, s)
+		})
+	}
+}
+
+func testStoreRecipients(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	queue, _ := s.PairingQueue(ctx)
+	if len(queue) != 2 || queue[0].Id != "1" || queue[1].Id != "2" {
+		t.Errorf("Expected recursers 1 and 2 in the pairing queue, got %v", queue)
+	}
+
+	// Grace is skipping so she shouldn't show up even though she'd get a question on Saturday
+	recipients, _ := s.SoloRecipients(ctx, "sat")
+	if len(recipients) != 0 {
+		t.Errorf("Expected no solo recipients on sat, got %v", recipients)
+	}
+
+	recipients, _ = s.SoloRecipients(ctx, "mon")
+	if len(recipients) != 2 || recipients[0].Id != "1" || recipients[1].Id != "3" {
+		t.Errorf("Expected recursers 1 and 3 to get questions on mon, got %v", recipients)
+	}
+
+	skippers, _ := s.Skippers(ctx)
+	if len(skippers) != 1 || skippers[0].Id != "2" {
+		t.Errorf("Expected recurser 2 to be skipping, got %v", skippers)
+	}
+}
+
+func TestStoreSessions(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			testStoreSessions(t, s)
+		})
+	}
+}
+
+func testStoreSessions(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	session := SoloSession{Question: 1, TimeStamp: time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)}
+
+	// appending the same element twice behaves like firestore.ArrayUnion
+	for i := 0; i < 2; i++ {
+		if err := s.AppendSoloSession(ctx, "1", session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := soloSessionCount(t, s, "1"); got != 1 {
+		t.Errorf("Expected 1 session, got %v", got)
+	}
+
+	review := SoloSession{Question: 3, TimeStamp: time.Date(2021, 3, 2, 11, 0, 0, 0, time.UTC), Review: true}
+	if err := s.AppendSoloSession(ctx, "1", review); err != nil {
+		t.Fatal(err)
+	}
+	review.Rating = 4
+	if err := s.UpdateLastSoloSession(ctx, "1", review); err != nil {
+		t.Fatal(err)
+	}
+	soloSessions, err := s.SoloSessions(ctx, "1")
+	if err != nil || len(soloSessions) != 2 || soloSessions[0].Rating != 0 || soloSessions[1] != review {
+		t.Errorf("Expected only the rated review to change, got %v (%v)", soloSessions, err)
+	}
+	if err := s.UpdateLastSoloSession(ctx, "2", review); err == nil {
+		t.Errorf("Expected an error updating a session that doesn't exist")
+	}
+
+	if questions, _ := s.Questions(ctx, QuestionQuery{Id: 26}); len(questions) != 1 || questions[0].Id != 26 {
+		t.Errorf("Expected question 26 only, got %v", questions)
+	}
+
+	triad := PairingSession{
+		Interviewer: "2",
+		Interviewee: "1",
+		Question:    3,
+		TimeStamp:   time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC),
+		Rotation:    []string{"2", "1", "3"},
+	}
+	if err := s.AppendPairingSession(ctx, "1", triad); err != nil {
+		t.Fatal(err)
+	}
+	pairingSessions, err := s.PairingSessions(ctx, "1")
+	if err != nil || len(pairingSessions) != 1 || !reflect.DeepEqual(pairingSessions[0].Rotation, triad.Rotation) {
+		t.Errorf("Expected the triad session %v, got %v (%v)", triad, pairingSessions, err)
+	}
+
+	if err := s.AppendSoloSession(ctx, "unknown", session); err == nil {
+		t.Errorf("Expected an error appending to a missing session history")
+	}
+
+	if err := s.CreateSessionHistory(ctx, "1"); err == nil {
+		t.Errorf("Expected an error creating an existing session history")
+	}
+
+	if err := s.CreateDailyQuestion(ctx, "March-1-2021", DailyQuestion{Question: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateDailyQuestion(ctx, "March-1-2021", DailyQuestion{Question: 3}); err == nil {
+		t.Errorf("Expected an error recording a second daily question for the same day")
+	}
+}
+
+func TestStoreJobRuns(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+
+			if got, err := s.LastJobRun(ctx, "solo"); err != nil || !got.IsZero() {
+				t.Errorf("Expected no run yet, got %v (%v)", got, err)
+			}
+			var last time.Time
+			for _, hour := range []int{9, 10} {
+				at := time.Date(2026, 10, 19, hour, 0, 0, 0, time.UTC)
+				if ok, err := s.RecordJobRun(ctx, "solo", last, at); err != nil || !ok {
+					t.Fatalf("Expected the %v run to be recorded, got %v (%v)", at, ok, err)
+				}
+				last = at
+			}
+			// a run recorded over a stale last run loses
+			for _, stale := range []time.Time{{}, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)} {
+				if ok, err := s.RecordJobRun(ctx, "solo", stale, time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)); err != nil || ok {
+					t.Errorf("Expected a run over %v not to be recorded, got %v (%v)", stale, ok, err)
+				}
+			}
+			want := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+			if got, err := s.LastJobRun(ctx, "solo"); err != nil || !got.Equal(want) {
+				t.Errorf("Expected %v, got %v (%v)", want, got, err)
+			}
+			if got, _ := s.LastJobRun(ctx, "pairs"); !got.IsZero() {
+				t.Errorf("Expected jobs to be recorded separately, got %v", got)
+			}
+		})
+	}
+}
+
+func TestStoreDeliveries(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			at := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+
+			for i, want := range []bool{true, false} {
+				if got, err := s.ClaimDelivery(ctx, Delivery{Run: "solo:2026-10-19", Recipient: "1", TimeStamp: at}); err != nil || got != want {
+					t.Errorf("Claim %v: Expected %v, got %v (%v)", i, want, got, err)
+				}
+			}
+			if got, _ := s.ClaimDelivery(ctx, Delivery{Run: "solo:2026-10-20", Recipient: "1", TimeStamp: at}); !got {
+				t.Errorf("Expected runs to be claimed separately")
+			}
+
+			want := []Delivery{{Run: "solo:2026-10-19", Recipient: "1", TimeStamp: at}}
+			got, err := s.Deliveries(ctx, "solo:2026-10-19")
+			if err != nil || len(got) != 1 || got[0].Recipient != "1" || !got[0].TimeStamp.Equal(at) {
+				t.Errorf("Expected %v, got %v (%v)", want, got, err)
+			}
+
+			if err := s.ReleaseDelivery(ctx, "solo:2026-10-19", "1"); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := s.ClaimDelivery(ctx, Delivery{Run: "solo:2026-10-19", Recipient: "1", TimeStamp: at}); !got {
+				t.Errorf("Expected a released delivery to be claimable again")
+			}
+		})
+	}
+}
+
+func TestStoreRecursers(t *testing.T) {
+	for backend, s := range testStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			testStoreRecursers(t, s)
+		})
+	}
+}
+
+func testStoreRecursers(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	recurser := newRecurser("5", "Barbara Liskov", "barbara@example.com")
+	if err := s.PutRecurser(ctx, recurser); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := s.GetRecurser(ctx, "5")
+	if err != nil || !ok {
+		t.Fatalf("Expected recurser 5 to exist: %v", err)
+	}
+	if !reflect.DeepEqual(got, recurser) {
+		t.Errorf("Expected %v, got %v", recurser, got)
+	}
+
+	config := recurser.Config
+	config.Topics = []string{"heap", "trie"}
+	config.ManualQuestion = true
+	config.Sequential = true
+	config.AdaptiveDifficulty = true
+	config.WeakTopics = true
+	config.SoloTime = "07:00"
+	config.Timezone = "Europe/London"
+	if err = s.UpdateConfig(ctx, "5", config); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ = s.GetRecurser(ctx, "5")
+	if !reflect.DeepEqual(got.Config, config) {
+		t.Errorf("Expected %v, got %v", config, got.Config)
+	}
+
+	recurser.IsPairingTomorrow = true
+	recurser.QueuedAt = time.Date(2026, 10, 16, 14, 30, 0, 0, time.UTC)
+	recurser.UnmatchedDays = 2
+	recurser.RematchWith = []string{"1"}
+	recurser.SkipDates = []string{"2026-10-20", "2026-11-02"}
+	recurser.Pauses = []Pause{{Start: "2026-10-21", End: "2026-10-30", Pairing: true}}
+	if err = s.PutRecurser(ctx, recurser); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _ = s.GetRecurser(ctx, "5")
+	if !got.QueuedAt.Equal(recurser.QueuedAt) || got.UnmatchedDays != 2 {
+		t.Errorf("Expected queued at %v with 2 unmatched days, got %v with %v", recurser.QueuedAt, got.QueuedAt, got.UnmatchedDays)
+	}
+	if !reflect.DeepEqual(got.RematchWith, recurser.RematchWith) {
+		t.Errorf("Expected rematches %v, got %v", recurser.RematchWith, got.RematchWith)
+	}
+	if !reflect.DeepEqual(got.SkipDates, recurser.SkipDates) {
+		t.Errorf("Expected skip dates %v, got %v", recurser.SkipDates, got.SkipDates)
+	}
+	if !reflect.DeepEqual(got.Pauses, recurser.Pauses) {
+		t.Errorf("Expected pauses %v, got %v", recurser.Pauses, got.Pauses)
+	}
+
+	// an update leaves everything it doesn't set alone
+	pairing, queuedAt, unmatchedDays, skipDates := false, time.Time{}, 0, []string{"2026-11-02"}
+	update := RecurserUpdate{IsPairingTomorrow: &pairing, QueuedAt: &queuedAt, UnmatchedDays: &unmatchedDays, SkipDates: &skipDates}
+	if err = s.UpdateRecurser(ctx, "5", update); err != nil {
+		t.Fatal(err)
+	}
+	want := recurser
+	want.IsPairingTomorrow, want.QueuedAt, want.UnmatchedDays, want.SkipDates = pairing, queuedAt, unmatchedDays, skipDates
+	got, _, _ = s.GetRecurser(ctx, "5")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if err = s.UpdateRecurser(ctx, "6", update); err == nil {
+		t.Errorf("Expected an error updating a missing recurser")
+	}
+
+	if err = s.UpdateConfig(ctx, "6", config); err == nil {
+		t.Errorf("Expected an error updating the config of a missing recurser")
+	}
+
+	if err = s.DeleteRecurser(ctx, "5"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = s.GetRecurser(ctx, "5"); ok {
+		t.Errorf("Expected recurser 5 to be deleted")
+	}
+
+	token, err := s.BotToken(ctx)
+	if err != nil || token != "test-bot-token" {
+		t.Errorf("Expected the bot token from fixtures, got %q (%v)", token, err)
+	}
+	key, err := s.APIKey(ctx)
+	if err != nil || key != "test-api-key" {
+		t.Errorf("Expected the API key from fixtures, got %q (%v)", key, err)
+	}
+	secret, err := s.LinkSecret(ctx)
+	if err != nil || secret != "test-link-secret" {
+		t.Errorf("Expected the link secret from fixtures, got %q (%v)", secret, err)
+	}
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "algobot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "algobot.db")
+
+	// reopening an existing database must not reapply anything
+	for i := 0; i < 2; i++ {
+		s, err := NewSQLiteStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var version int
+		if err = s.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != len(sqliteMigrations) {
+			t.Errorf("Expected schema version %v, got %v", len(sqliteMigrations), version)
+		}
+		s.Close()
+	}
+}
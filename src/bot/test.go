@@ -1,109 +0,0 @@
-package bot
-
-import (
-	"context"
-	"io"
-	"log"
-	"os"
-	"os/exec"
-	"strings"
-	"sync"
-	"syscall"
-	"testing"
-
-	"cloud.google.com/go/firestore"
-)
-
-const FirestoreEmulatorHost = "FIRESTORE_EMULATOR_HOST"
-
-// https://www.captaincodeman.com/2020/03/04/unit-testing-with-firestore-emulator-and-go
-func TestMain(m *testing.M) {
-	initEmulator(m)
-	ctx := context.Background()
-	client := newFirestoreTestClient(ctx)
-	defer client.Close()
-	// loadTestData(
-}
-
-func initEmulator(m *testing.M) {
-	// command to start firestore emulator
-	cmd := exec.Command("gcloud", "beta", "emulators", "firestore", "start", "--host-port=localhost")
-
-	// this makes it killable
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-
-	// we need to capture it's output to know when it's started
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer stderr.Close()
-
-	// start her up!
-	if err := cmd.Start(); err != nil {
-		log.Fatal(err)
-	}
-
-	// ensure the process is killed when we're finished, even if an error occurs
-	// (thanks to Brian Moran for suggestion)
-	var result int
-	defer func() {
-		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-		os.Exit(result)
-	}()
-
-	// we're going to wait until it's running to start
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	// by starting a separate go routine
-	go func() {
-		// reading it's output
-		buf := make([]byte, 256, 256)
-		for {
-			n, err := stderr.Read(buf[:])
-			if err != nil {
-				// until it ends
-				if err == io.EOF {
-					break
-				}
-				log.Fatalf("reading stderr %v", err)
-			}
-
-			if n > 0 {
-				d := string(buf[:n])
-
-				// only required if we want to see the emulator output
-				log.Printf("%s", d)
-
-				// checking for the message that it's started
-				if strings.Contains(d, "Dev App Server is now running") {
-					wg.Done()
-				}
-
-				// and capturing the FIRESTORE_EMULATOR_HOST value to set
-				pos := strings.Index(d, FirestoreEmulatorHost+"=")
-				if pos > 0 {
-					host := d[pos+len(FirestoreEmulatorHost)+1 : len(d)-1]
-					os.Setenv(FirestoreEmulatorHost, host)
-				}
-			}
-		}
-	}()
-
-	// wait until the running message has been received
-	wg.Wait()
-
-	// now it's running, we can run our unit tests
-	result = m.Run()
-
-}
-
-func newFirestoreTestClient(ctx context.Context) *firestore.Client {
-	client, err := firestore.NewClient(ctx, "test")
-	if err != nil {
-		log.Fatalf("firebase.NewClient err: %v", err)
-	}
-
-	return client
-}
@@ -2,12 +2,12 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"math/rand"
+	"sort"
+	"strings"
 	"time"
-
-	"cloud.google.com/go/firestore"
-	"google.golang.org/api/iterator"
 )
 
 func contains(list []string, cmd string) bool {
@@ -19,6 +19,87 @@ func contains(list []string, cmd string) bool {
 	return false
 }
 
+// containsAny reports whether any of values is in list
+func containsAny(list []string, values []string) bool {
+	for _, v := range values {
+		if contains(list, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// addString appends value to list unless it's already there
+func addString(list []string, value string) []string {
+	if contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+// removeString returns list without any occurrence of value
+func removeString(list []string, value string) []string {
+	kept := []string{}
+	for _, v := range list {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// inOrder returns the options that appear in values, in the order of options
+func inOrder(options []string, values []string) []string {
+	ordered := []string{}
+	for _, option := range options {
+		if contains(values, option) {
+			ordered = append(ordered, option)
+		}
+	}
+	return ordered
+}
+
+// diffLines lists the lines removed from before with "- " and the lines added
+// in after with "+ ", lining the two up by their longest common subsequence
+func diffLines(before string, after string) []string {
+	a := strings.Split(strings.TrimRight(before, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(after, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	return diff
+}
+
 func min(arr []string, strVals map[string]int) int {
 	min := strVals[arr[0]]
 	for i := 1; i < len(arr); i++ {
@@ -30,6 +111,13 @@ func min(arr []string, strVals map[string]int) int {
 	return min
 }
 
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
 // this shuffles our recursers.
 // TODO: source of randomness is time, but this runs at the
 // same time each day. Is that ok?
@@ -43,79 +131,233 @@ func shuffle(slice []Recurser) {
 	slice = ret
 }
 
-func selectQuestion(recurser Recurser, client *firestore.Client, ctx context.Context) map[string]interface{} {
+// selectQuestion picks a random question that fits the recurser's config and
+// that they haven't been sent before, whether as a solo question or as an
+// interviewee. Once they've had every question that fits, it falls back to the
+// one they had longest ago and reports that it's a repeat. With pairing set
+// it's a question for them to be interviewed on, at their pairing difficulty.
+func selectQuestion(recurser Recurser, pairing bool, store Store, now time.Time, ctx context.Context) (*Question, bool) {
 	config := recurser.Config
 
-	if config.ManualQuestion {
-		return nil
-	}
-
 	s := rand.NewSource(time.Now().UnixNano())
 	r := rand.New(s)
 
-	difficulty := config.SoloDifficulty[r.Intn(len(config.SoloDifficulty))]
-	query := client.Collection("questions").Where("difficulty", "==", difficulty)
+	received := questionHistory(recurser.Id, store, ctx)
+
+	// recursers walking their pset in order get the next one they haven't had
+	// as a solo question, and go back to random questions once they're
+	// through it. Interviews don't move them along.
+	if config.Sequential && config.ProblemSet != "random" && !pairing {
+		questions, err := psetQuestions(config.ProblemSet, store, ctx)
+		if err != nil {
+			log.Println(err)
+			return nil, false
+		}
+		if next := notReceived(questions, soloHistory(recurser.Id, store, ctx)); len(next) != 0 {
+			return &next[0], false
+		}
+	}
+
+	// the difficulties to choose from are the config's unless solo questions
+	// adapt; interviews stick to the pairing difficulties
+	var mix difficultyMix
+	if pairing {
+		config.SoloDifficulty = config.PairingDifficulty
+		mix = evenMix(config)
+	} else {
+		mix = currentDifficultyMix(recurser, store, ctx)
+	}
+	config.SoloDifficulty = mix.difficulties()
 
-	if config.Topics != nil && len(config.Topics) != 0 {
-		topic := config.Topics[r.Intn(len(config.Topics))]
-		query = query.Where("tags", "array-contains", topic)
+	// first try a difficulty drawn from the mix and a random topic so that
+	// the odds of each are what they should be
+	query := QuestionQuery{
+		ProblemSet: config.ProblemSet,
+		Difficulty: mix.pick(r),
+		Tag:        selectTopic(recurser, pairing, r, store, now, ctx),
 	}
 
-	if config.ProblemSet != "random" {
-		query = query.Where("psets", "array-contains", config.ProblemSet)
+	questions, err := store.Questions(ctx, query)
+	if err != nil {
+		log.Println(err)
+		return nil, false
 	}
 
-	iter := query.Documents(ctx)
-	var documents []*firestore.DocumentSnapshot
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+	if fresh := notReceived(questions, received); len(fresh) != 0 {
+		selection := fresh[r.Intn(len(fresh))]
+		return &selection, false
+	}
+
+	// then anything else that fits the config
+	questions, err = store.Questions(ctx, QuestionQuery{ProblemSet: config.ProblemSet})
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	questions = fitConfig(questions, config)
+
+	if fresh := notReceived(questions, received); len(fresh) != 0 {
+		selection := fresh[r.Intn(len(fresh))]
+		return &selection, false
+	}
+
+	if len(questions) == 0 {
+		return nil, false
+	}
+
+	selection := questions[0]
+	for _, question := range questions[1:] {
+		if received[question.Id].Before(received[selection.Id]) {
+			selection = question
 		}
-		if err != nil {
-			log.Panic(err)
+	}
+	return &selection, true
+}
+
+// selectTopic draws the topic to look for a question on first, or "" for any.
+// Only solo questions lean towards weak topics; interviews are drawn evenly
+// from the topics in the config.
+func selectTopic(recurser Recurser, pairing bool, r *rand.Rand, store Store, now time.Time, ctx context.Context) string {
+	config := recurser.Config
+	switch {
+	case config.WeakTopics && !pairing:
+		return pickTopic(currentWeakness(recurser, store, now, ctx), r)
+	case len(config.Topics) != 0:
+		return config.Topics[r.Intn(len(config.Topics))]
+	}
+	return ""
+}
+
+// questionHistory is when the recurser was last sent each question, either
+// as a solo question or as the interviewee in a pairing session
+func questionHistory(userID string, store Store, ctx context.Context) map[int]time.Time {
+	received := soloHistory(userID, store, ctx)
+	add := func(question int, when time.Time) {
+		if question != 0 && when.After(received[question]) {
+			received[question] = when
 		}
-		documents = append(documents, doc)
 	}
 
-	if len(documents) == 0 {
-		return nil
+	pairingSessions, err := store.PairingSessions(ctx, userID)
+	if err != nil {
+		log.Println(err)
 	}
+	for _, session := range pairingSessions {
+		if session.Interviewee == userID {
+			add(session.Question, session.TimeStamp)
+		}
+	}
+
+	return received
+}
+
+// soloHistory is when the recurser was last sent each question as a solo
+// question, which is how far through their pset they are
+func soloHistory(userID string, store Store, ctx context.Context) map[int]time.Time {
+	received := make(map[int]time.Time)
 
-	selection := documents[r.Intn(len(documents))]
+	sessions, err := store.SoloSessions(ctx, userID)
+	if err != nil {
+		log.Println(err)
+	}
+	for _, session := range sessions {
+		if session.Question != 0 && session.TimeStamp.After(received[session.Question]) {
+			received[session.Question] = session.TimeStamp
+		}
+	}
 
-	return selection.Data()
+	return received
 }
 
-func iterToRecurserList(iter *firestore.DocumentIterator) []Recurser {
-	var recursersList []Recurser
+// lookupQuestions finds the questions of the given solo sessions by id.
+// Any that can't be found are left out.
+func lookupQuestions(sessions []SoloSession, store Store, ctx context.Context) map[int]Question {
+	var ids []int
+	for _, session := range sessions {
+		ids = append(ids, session.Question)
+	}
+	return lookupQuestionIDs(ids, store, ctx)
+}
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+// lookupQuestionIDs fetches each of the questions by ID, leaving out any it
+// can't find
+func lookupQuestionIDs(ids []int, store Store, ctx context.Context) map[int]Question {
+	questions := make(map[int]Question)
+	for _, id := range ids {
+		if _, ok := questions[id]; ok || id == 0 {
+			continue
 		}
+		found, err := store.Questions(ctx, QuestionQuery{Id: id})
 		if err != nil {
-			log.Panic(err)
+			log.Println(err)
+			continue
 		}
+		if len(found) > 0 {
+			questions[id] = found[0]
+		}
+	}
+	return questions
+}
+
+// psetQuestions is every question in the problem set, in the order the pset
+// lists them. Any it doesn't list come last, by ID.
+func psetQuestions(pset string, store Store, ctx context.Context) ([]Question, error) {
+	questions, err := store.Questions(ctx, QuestionQuery{ProblemSet: pset})
+	if err != nil {
+		return nil, err
+	}
+	order, err := store.ProblemSet(ctx, pset)
+	if err != nil {
+		return nil, err
+	}
 
-		var recurser Recurser
-		if err = doc.DataTo(&recurser); err != nil {
-			log.Fatal(err)
+	position := make(map[int]int)
+	for i, id := range order {
+		if _, ok := position[id]; !ok {
+			position[id] = i
 		}
-		recursersList = append(recursersList, recurser)
 	}
+	sort.Slice(questions, func(i, j int) bool {
+		pi, iListed := position[questions[i].Id]
+		pj, jListed := position[questions[j].Id]
+		switch {
+		case iListed && jListed:
+			return pi < pj
+		case iListed != jListed:
+			return iListed
+		}
+		return questions[i].Id < questions[j].Id
+	})
+	return questions, nil
+}
 
-	return recursersList
+// notReceived filters out questions the recurser has already been sent
+func notReceived(questions []Question, received map[int]time.Time) []Question {
+	var fresh []Question
+	for _, question := range questions {
+		if _, ok := received[question.Id]; !ok {
+			fresh = append(fresh, question)
+		}
+	}
+	return fresh
 }
 
-func isValidMatch(recurserOne Recurser, recurserTwo Recurser) bool {
-	difficulties := map[string]int{
-		"easy":   0,
-		"medium": 1,
-		"hard":   2,
+// fitConfig filters questions down to the config's difficulties and topics
+func fitConfig(questions []Question, config UserConfig) []Question {
+	var fits []Question
+	for _, question := range questions {
+		if len(config.SoloDifficulty) != 0 && !contains(config.SoloDifficulty, question.Difficulty) {
+			continue
+		}
+		if len(config.Topics) != 0 && !containsAny(question.Tags, config.Topics) {
+			continue
+		}
+		fits = append(fits, question)
 	}
+	return fits
+}
 
-	return min(recurserOne.Config.PairingDifficulty, difficulties) <= difficulties[recurserTwo.Config.Experience] &&
-		min(recurserTwo.Config.PairingDifficulty, difficulties) <= difficulties[recurserOne.Config.Experience]
+func isValidMatch(recurserOne Recurser, recurserTwo Recurser) bool {
+	return min(recurserOne.Config.PairingDifficulty, difficultyLevels) <= difficultyLevels[recurserTwo.Config.Experience] &&
+		min(recurserTwo.Config.PairingDifficulty, difficultyLevels) <= difficultyLevels[recurserOne.Config.Experience]
 }
@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Weak-topic targeting favours the topics a recurser struggles with. Every
+// topic starts with a weight of 1 and gains up to 2 for a low success rate,
+// up to 1 for solves that take longer than their average and up to 1 for not
+// having come up recently, so the weakest topic is at most five times as
+// likely as the strongest.
+const (
+	weaknessWindow = 60 // how many recent solo sessions count
+	failureWeight  = 2
+	slownessWeight = 1
+	stalenessDays  = 14 // a topic this long unseen gets the full staleness weight
+)
+
+// topicWeakness is how a recurser has been doing on one topic
+type topicWeakness struct {
+	topic        string
+	attempts     int // sessions on the topic, finished or not
+	rated        int // attempts we know the outcome of
+	successes    int // rated attempts that went well
+	timedSolves  int
+	solveMinutes int
+	lastSeen     time.Time
+	weight       float64
+}
+
+// measureWeakness weighs each of the topics by the recurser's solo sessions,
+// weakest first. Questions with several tags count towards each.
+func measureWeakness(topics []string, sessions []SoloSession, questions map[int]Question, now time.Time) []topicWeakness {
+	weaknesses := make([]topicWeakness, len(topics))
+	index := make(map[string]int)
+	for i, topic := range topics {
+		weaknesses[i].topic = topic
+		index[topic] = i
+	}
+
+	timedSolves, solveMinutes := 0, 0
+	for _, session := range sessions {
+		question, ok := questions[session.Question]
+		if !ok {
+			continue
+		}
+		timed := session.Outcome == outcomeSolved && session.Minutes > 0
+		if timed {
+			timedSolves++
+			solveMinutes += session.Minutes
+		}
+
+		for _, tag := range question.Tags {
+			i, ok := index[tag]
+			if !ok {
+				continue
+			}
+			w := &weaknesses[i]
+			w.attempts++
+			if rating := attemptRating(session); rating != 0 {
+				w.rated++
+				if rating >= passingRating {
+					w.successes++
+				}
+			}
+			if timed {
+				w.timedSolves++
+				w.solveMinutes += session.Minutes
+			}
+			if session.TimeStamp.After(w.lastSeen) {
+				w.lastSeen = session.TimeStamp
+			}
+		}
+	}
+
+	for i := range weaknesses {
+		w := &weaknesses[i]
+		w.weight = 1
+
+		// a topic we know nothing about counts as half solved
+		failureRate := 0.5
+		if w.rated > 0 {
+			failureRate = 1 - float64(w.successes)/float64(w.rated)
+		}
+		w.weight += failureWeight * failureRate
+
+		if w.timedSolves > 0 && timedSolves > 0 {
+			slowness := w.averageMinutes()/(float64(solveMinutes)/float64(timedSolves)) - 1
+			w.weight += slownessWeight * math.Max(0, math.Min(1, slowness))
+		}
+
+		staleness := 1.0
+		if !w.lastSeen.IsZero() {
+			staleness = math.Min(1, now.Sub(w.lastSeen).Hours()/24/stalenessDays)
+		}
+		w.weight += staleness
+	}
+
+	sort.SliceStable(weaknesses, func(i, j int) bool {
+		return weaknesses[i].weight > weaknesses[j].weight
+	})
+	return weaknesses
+}
+
+func (w topicWeakness) averageMinutes() float64 {
+	return float64(w.solveMinutes) / float64(w.timedSolves)
+}
+
+// weaknessTopics are the topics weighed for the recurser: the ones they're
+// focusing on, or all of them
+func weaknessTopics(config UserConfig) []string {
+	if len(config.Topics) != 0 {
+		return config.Topics
+	}
+	return topicOptions
+}
+
+// currentWeakness measures the recurser's topics from their solo sessions
+func currentWeakness(recurser Recurser, store Store, now time.Time, ctx context.Context) []topicWeakness {
+	sessions, err := store.SoloSessions(ctx, recurser.Id)
+	if err != nil {
+		log.Println(err)
+	}
+	if len(sessions) > weaknessWindow {
+		sessions = sessions[len(sessions)-weaknessWindow:]
+	}
+	return measureWeakness(weaknessTopics(recurser.Config), sessions, lookupQuestions(sessions, store, ctx), now)
+}
+
+// pickTopic draws a topic with odds in proportion to its weight
+func pickTopic(weaknesses []topicWeakness, r *rand.Rand) string {
+	total := 0.0
+	for _, w := range weaknesses {
+		total += w.weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	x := r.Float64() * total
+	for _, w := range weaknesses {
+		x -= w.weight
+		if x < 0 {
+			return w.topic
+		}
+	}
+	return weaknesses[len(weaknesses)-1].topic
+}
+
+// fmtWeakness describes how a topic has gone, e.g. "1 of 3 went well, 40
+// minutes per solve, last seen 12 days ago"
+func fmtWeakness(w topicWeakness, now time.Time) string {
+	if w.attempts == 0 {
+		return "not seen yet"
+	}
+
+	var parts []string
+	if w.rated > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d went well", w.successes, w.rated))
+	} else {
+		parts = append(parts, fmt.Sprintf("%s, none finished", pluralize(w.attempts, "attempt")))
+	}
+	if w.timedSolves > 0 {
+		parts = append(parts, fmt.Sprintf("%s per solve", pluralize(int(math.Round(w.averageMinutes())), "minute")))
+	}
+	parts = append(parts, fmt.Sprintf("last seen %s ago", fmtDaysAgo(w.lastSeen, now)))
+	return strings.Join(parts, ", ")
+}
@@ -7,22 +7,39 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+)
 
-	"cloud.google.com/go/firestore"
-	"github.com/fatih/structs"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+// Where the bot is reached and who it is on Zulip. main sets these from the
+// environment at startup.
+var (
+	botEmailAddress string
+	zulipServerURL  string
+	serverURL       string
 )
 
-const botEmailAddress = "algo-bot@recurse.zulipchat.com"
-const zulipAPIURL = "https://recurse.zulipchat.com/api/v1/messages"
-const gcloudServerURL = "https://algobot-308118.ue.r.appspot.com"
+// SetZulip changes the Zulip server the bot talks to and its email address there
+func SetZulip(url string, email string) {
+	zulipServerURL = url
+	botEmailAddress = email
+}
+
+// SetServerURL changes the address links to the bot's pages start with
+func SetServerURL(url string) {
+	serverURL = strings.TrimSuffix(url, "/")
+}
+
+// dateLayout is how dates are written in commands and stored, e.g. 2026-11-02
+const dateLayout = "2006-01-02"
+
+// timeLayout is how times of day are stored, e.g. 09:00
+const timeLayout = "15:04"
 
 var botMessages = InitMessenger("src/bot/messages.json")
-var client *firestore.Client
 
 // This is a struct that gets only what
 // we need from the incoming JSON payload
@@ -55,14 +72,6 @@ func Webhook(w http.ResponseWriter, r *http.Request) {
 	responder := json.NewEncoder(w)
 	ctx := context.Background()
 
-	var err error
-	client, err = firestore.NewClient(ctx, "algobot-308118")
-	defer client.Close()
-
-	if err != nil {
-		log.Panic(err)
-	}
-
 	// sanity check the incoming request
 	userReq, err := sanityCheck(ctx, w, r)
 	if err != nil {
@@ -90,15 +99,8 @@ func Webhook(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	// you *should* be able to throw any freakin string at this thing and get back a valid command for dispatch()
-	// if there are no commad arguments, cmdArgs will be nil
-	cmd, cmdArgs, err := parseCmd(userReq.Data)
-	if err != nil {
-		log.Println(err)
-	}
-
-	// the tofu and potatoes right here y'all
-	response, err := dispatch(ctx, cmd, cmdArgs, strconv.Itoa(userReq.Message.SenderID), userReq.Message.SenderEmail, userReq.Message.SenderFullName)
+	// you *should* be able to throw any freakin string at this thing and get back a valid response
+	response, err := handleCommand(ctx, userReq.Data, strconv.Itoa(userReq.Message.SenderID), userReq.Message.SenderEmail, userReq.Message.SenderFullName)
 	if err != nil {
 		log.Println(err)
 	}
@@ -120,13 +122,12 @@ func sanityCheck(ctx context.Context, w http.ResponseWriter, r *http.Request) (i
 	}
 
 	// validate our zulip-bot token (manually put into the database before deployment)
-	doc, err := client.Collection("auth").Doc("bot").Get(ctx)
+	token, err := store.BotToken(ctx)
 	if err != nil {
 		log.Println("Something weird happened trying to read the auth token from the database")
 		return userReq, err
 	}
 
-	token := doc.Data()["token"]
 	if userReq.Token != token {
 		http.NotFound(w, r)
 		return userReq, errors.New("unauthorized interaction attempt")
@@ -135,123 +136,227 @@ func sanityCheck(ctx context.Context, w http.ResponseWriter, r *http.Request) (i
 	return userReq, err
 }
 
-func parseCmd(cmdStr string) (string, []string, error) {
-	var err error
-	var cmdList = []string{
-		"cancel",
-		"config",
-		"help",
-		"schedule",
-		"skip",
-		"subscribe",
-		"unskip",
-		"unsubscribe",
-	}
-
-	// convert the string to a slice
-	// after this, we have a value "cmd" of type []string
-	// where cmd[0] is the command and cmd[1:] are any arguments
-	space := regexp.MustCompile(`\s+`)
-	cmdStr = space.ReplaceAllString(cmdStr, ` `)
-	cmdStr = strings.TrimSpace(cmdStr)
-	cmdStr = strings.ToLower(cmdStr)
-	cmd := strings.Split(cmdStr, ` `)
-
-	// Big validation logic -- hellooo darkness my old frieeend
-	switch {
-	// if there's nothing in the command string srray
-	case len(cmd) == 0:
-		err = errors.New("the user-issued command was blank")
-		return "help", nil, err
+func config(userID string, recurser Recurser, isSubscribed bool) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+	// Provide current settings as well as user-specific URL for config
+	var response string
+	response = recurser.stringifyUserConfig()
+	response += fmt.Sprintf("[Click here to make changes](%s) (the link works for a day)", pageLink("config", userID, time.Now()))
 
-	// if there's a valid command and if there's no arguments
-	case contains(cmdList, cmd[0]) && len(cmd) == 1:
-		return cmd[0], nil, err
+	return response
+}
 
-	// if there's not a valid command
+func progress(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	pset := recurser.Config.ProblemSet
+	if pset == "random" {
+		return "You're getting questions from all over rather than from a problem set, so there's no progress to track! " +
+			"Use `set pset topInterview` or `set pset top100Liked` to work through one."
+	}
+
+	questions, err := psetQuestions(pset, store, ctx)
+	if err != nil {
+		return botMessages.ReadError
+	}
+	if len(questions) == 0 {
+		return fmt.Sprintf("I don't have any questions for the %s pset yet!", pset)
+	}
+	received := soloHistory(userID, store, ctx)
+
+	// tally how many questions there are and how many they've had as solo
+	// questions, per topic
+	done := 0
+	var topicNames []string
+	topicTotals := make(map[string]int)
+	topicDone := make(map[string]int)
+	var next *Question
+	for i, question := range questions {
+		_, had := received[question.Id]
+		if had {
+			done++
+		} else if next == nil {
+			next = &questions[i]
+		}
+		for _, tag := range question.Tags {
+			if topicTotals[tag] == 0 {
+				topicNames = append(topicNames, tag)
+			}
+			topicTotals[tag]++
+			if had {
+				topicDone[tag]++
+			}
+		}
+	}
+	sort.Strings(topicNames)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You've done %d of the %d questions in the %s pset (%d%%)", done, len(questions), pset, 100*done/len(questions)))
+	switch {
+	case next == nil:
+		b.WriteString(". You finished it, congrats!!\n")
+	case recurser.Config.Sequential:
+		b.WriteString(fmt.Sprintf(" and are working through it in order. Up next is [%s](%s).\n", next.Name, next.URL))
 	default:
-		err = errors.New("the user-issued command wasn't valid")
-		return "help", nil, err
+		b.WriteString(". Use `set order sequential` to work through the rest in order.\n")
 	}
-}
 
-// TODO: Docstring here!
-func dispatch(ctx context.Context, cmd string, cmdArgs []string, userID string, userEmail string, userName string) (string, error) {
-	var response string
-	var recurser Recurser
+	b.WriteString("\n**By topic:**\n")
+	for _, topic := range topicNames {
+		b.WriteString(fmt.Sprintf("* %s: %d/%d\n", topic, topicDone[topic], topicTotals[topic]))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
 
-	// get the users "document" (database entry) out of firestore and temporarily keep it in 'doc'
-	doc, err := client.Collection("recursers").Doc(userID).Get(ctx)
+func rate(userID string, recurser Recurser, isSubscribed bool, rating string, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
 
-	// this says "if there's an error, and if that error was not document-not-found"
-	if err != nil && grpc.Code(err) != codes.NotFound {
-		response = botMessages.ReadError
-		return response, err
+	sessions, failure := updateLastAttempt(userID, "rate", func(session *SoloSession) {
+		session.Rating, _ = strconv.Atoi(rating)
+	}, ctx)
+	if failure != "" {
+		return failure
 	}
 
-	// if there's a db entry, that means they were already subscribed to pairing bot
-	isSubscribed := doc.Exists()
+	last := sessions[len(sessions)-1]
+	card := reviewSchedule(sessions)[last.Question]
+	return fmt.Sprintf("Thanks for rating it %d/%d! I'll bring that question back for review around %s.", last.Rating, maxRating, card.due.Format("Monday, January 2"))
+}
 
-	// if the user is in the database, get their current state in a struct
-	if isSubscribed {
-		if err = doc.DataTo(&recurser); err != nil {
-			log.Fatal(err)
-		}
+func done(userID string, recurser Recurser, isSubscribed bool, minutes string, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
 	}
 
-	// here's the actual actions. command input from the user has already been sanitized,
-	// so we can trust that cmd and cmdArgs only have valid stuff in them
-	switch cmd {
+	sessions, failure := updateLastAttempt(userID, "mark as done", func(session *SoloSession) {
+		session.Outcome = outcomeSolved
+		session.Minutes, _ = strconv.Atoi(minutes)
+	}, ctx)
+	if failure != "" {
+		return failure
+	}
+
+	last := sessions[len(sessions)-1]
+	response := "Nice work solving it!"
+	if last.Minutes > 0 {
+		response = fmt.Sprintf("Nice work solving it in %s!", pluralize(last.Minutes, "minute"))
+	}
+	if streak := computeSoloStats(sessions, nil).currentStreak; streak > 1 {
+		response += fmt.Sprintf(" That's %d solved in a row :fire:", streak)
+	}
+	if last.Rating == 0 {
+		response += fmt.Sprintf("\n\nHow did it feel? `rate` it from 1 (couldn't solve it) to %d (easy).", maxRating)
+	}
+	return response
+}
 
-	case "config":
-		response = config(userID, recurser, isSubscribed)
-		break
+func gaveUp(userID string, recurser Recurser, isSubscribed bool, minutes string, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
 
-	case "schedule":
-		response = schedule(userID, recurser, isSubscribed, ctx)
-		break
+	sessions, failure := updateLastAttempt(userID, "give up on", func(session *SoloSession) {
+		session.Outcome = outcomeGaveUp
+		session.Minutes, _ = strconv.Atoi(minutes)
+	}, ctx)
+	if failure != "" {
+		return failure
+	}
 
-	case "cancel":
-		response = cancel(userID, recurser, isSubscribed, ctx)
-		break
+	last := sessions[len(sessions)-1]
+	card := reviewSchedule(sessions)[last.Question]
+	return fmt.Sprintf("No worries, it happens to everyone! Take a look at some solutions and I'll bring it back for another try around %s.", card.due.Format("Monday, January 2"))
+}
 
-	case "subscribe":
-		response = subscribe(userID, userName, userEmail, recurser, isSubscribed, ctx)
-		break
+// updateLastAttempt applies update to the recurser's latest solo session and
+// returns their history including the change. If that fails, it returns what
+// to tell them instead.
+func updateLastAttempt(userID string, verb string, update func(session *SoloSession), ctx context.Context) ([]SoloSession, string) {
+	sessions, err := store.SoloSessions(ctx, userID)
+	if err != nil {
+		return nil, botMessages.ReadError
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Sprintf("You haven't gotten a question from me yet, so there's nothing to %s!", verb)
+	}
 
-	case "unsubscribe":
-		response = unsubscribe(userID, recurser, isSubscribed, ctx)
-		break
+	last := &sessions[len(sessions)-1]
+	update(last)
+	err = store.UpdateLastSoloSession(ctx, userID, *last)
+	if err != nil {
+		return nil, botMessages.WriteError
+	}
+	return sessions, ""
+}
 
-	case "skip":
-		response = skip(userID, recurser, isSubscribed, ctx)
-		break
+// validateMinutes checks the time someone took on a question
+func validateMinutes(minutes string) error {
+	if n, err := strconv.Atoi(minutes); err != nil || n < 1 || n > 600 {
+		return fmt.Errorf("%q isn't a number of minutes between 1 and 600.", minutes)
+	}
+	return nil
+}
 
-	case "unskip":
-		response = unskip(userID, recurser, isSubscribed, ctx)
-		break
+// parseSoloTime reads a time of day like "9am", "9:00" or "21" and writes it
+// the way it's stored. Questions only go out on the hour.
+func parseSoloTime(value string) (string, error) {
+	for _, layout := range []string{timeLayout, "15", "3pm", "3:04pm"} {
+		t, err := time.Parse(layout, strings.ToLower(value))
+		if err != nil {
+			continue
+		}
+		if t.Minute() != 0 {
+			return "", fmt.Errorf("I only send questions on the hour, so %q won't work. Try %s instead.", value, t.Truncate(time.Hour).Format(timeLayout))
+		}
+		return t.Format(timeLayout), nil
+	}
+	return "", fmt.Errorf("%q isn't a time I understand. Try something like 9am or 21:00.", value)
+}
 
-	case "help":
-		response = botMessages.Help
-		break
+func validateSoloTime(value string) error {
+	_, err := parseSoloTime(value)
+	return err
+}
 
-	default:
-		// this won't execute because all input has been sanitized
-		// by parseCmd() and all cases are handled explicitly above
+func validateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil || name == "" || name == "Local" {
+		return fmt.Errorf("%q isn't a time zone I know. Try a name like America/New_York or Europe/London.", name)
 	}
-	return response, err
+	return nil
 }
 
-func config(userID string, recurser Recurser, isSubscribed bool) string {
+func stats(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
 	if isSubscribed == false {
 		return botMessages.NotSubscribed
 	}
-	// Provide current settings as well as user-specific URL for config
-	var response string
-	response = recurser.stringifyUserConfig()
-	response += fmt.Sprintf("[Click here to make changes](%s/config/%s)", gcloudServerURL, userID)
 
-	return response
+	sessions, err := store.SoloSessions(ctx, userID)
+	if err != nil {
+		return botMessages.ReadError
+	}
+	questions, err := store.Questions(ctx, QuestionQuery{})
+	if err != nil {
+		return botMessages.ReadError
+	}
+	byId := make(map[int]Question)
+	for _, question := range questions {
+		byId[question.Id] = question
+	}
+
+	return fmtSoloStats(computeSoloStats(sessions, byId))
+}
+
+func difficulty(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+	return fmtDifficultyMix(currentDifficultyMix(recurser, store, ctx), recurser.Config)
 }
 
 func schedule(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
@@ -262,8 +367,13 @@ func schedule(userID string, recurser Recurser, isSubscribed bool, ctx context.C
 		return botMessages.NotConfigured
 	}
 
+	// scheduling again while already queued keeps your place in line
+	if !recurser.IsPairingTomorrow {
+		recurser.QueuedAt = time.Now()
+		recurser.UnmatchedDays = 0
+	}
 	recurser.IsPairingTomorrow = true
-	_, err := client.Collection("recursers").Doc(userID).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	err := store.PutRecurser(ctx, recurser)
 	if err != nil {
 		return botMessages.WriteError
 	}
@@ -275,8 +385,11 @@ func schedule(userID string, recurser Recurser, isSubscribed bool, ctx context.C
 }
 
 func getQueueStatus(recurser Recurser, ctx context.Context) string {
-	iter := client.Collection("recursers").Where("isPairingTomorrow", "==", true).Documents(ctx)
-	recursersList := iterToRecurserList(iter)
+	recursersList, err := store.PairingQueue(ctx)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
 	possibleMatches := 0
 
 	for _, r := range recursersList {
@@ -285,7 +398,29 @@ func getQueueStatus(recurser Recurser, ctx context.Context) string {
 		}
 	}
 
-	return fmt.Sprintf("Of the %v other Recursers in the queue, %v are a valid match!", len(recursersList)-1, possibleMatches-1)
+	status := fmt.Sprintf("Of the %v other Recursers in the queue, %v are a valid match!", len(recursersList)-1, possibleMatches-1)
+	if wait := fmtWaitTime(recurser, time.Now()); wait != "" {
+		status += "\n" + wait
+	}
+	return status
+}
+
+// fmtWaitTime tells a recurser how long they've been waiting for a match
+func fmtWaitTime(recurser Recurser, now time.Time) string {
+	if recurser.QueuedAt.IsZero() {
+		return ""
+	}
+
+	var wait string
+	if days := daysQueued(recurser, now); days == 0 {
+		wait = "You joined the queue less than a day ago."
+	} else {
+		wait = fmt.Sprintf("You've been waiting for a match for %s.", pluralize(days, "day"))
+	}
+	if recurser.UnmatchedDays > 0 {
+		wait += fmt.Sprintf(" You went unmatched %s in a row, so you're near the front of the line!", pluralize(recurser.UnmatchedDays, "day"))
+	}
+	return wait
 }
 
 func cancel(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
@@ -296,7 +431,9 @@ func cancel(userID string, recurser Recurser, isSubscribed bool, ctx context.Con
 		return "You are not signed up to pair!"
 	}
 	recurser.IsPairingTomorrow = false
-	_, err := client.Collection("recursers").Doc(userID).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	recurser.QueuedAt = time.Time{}
+	recurser.UnmatchedDays = 0
+	err := store.PutRecurser(ctx, recurser)
 	if err != nil {
 		return botMessages.WriteError
 	}
@@ -304,78 +441,253 @@ func cancel(userID string, recurser Recurser, isSubscribed bool, ctx context.Con
 	return `Tomorrow: cancelled. I feel you. **I will not match you** for pairing tomorrow <3`
 }
 
+// partnerAgain lets someone opt in to being paired with a past partner even
+// if they met recently. The partner is given as a Zulip mention, e.g.
+// @**Grace Hopper** or @**Grace Hopper|2**.
+func partnerAgain(userID string, recurser Recurser, isSubscribed bool, cmdArgs []string, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	mention := strings.Join(cmdArgs, " ")
+	mention = strings.Trim(strings.TrimPrefix(mention, "@"), "*")
+	name, id := mention, ""
+	if i := strings.LastIndex(mention, "|"); i != -1 {
+		name, id = mention[:i], mention[i+1:]
+	}
+
+	sessions, err := store.PairingSessions(ctx, userID)
+	if err != nil {
+		return botMessages.ReadError
+	}
+
+	var partner Recurser
+	found := false
+	for _, session := range sessions {
+		partnerID := session.Interviewer
+		if partnerID == userID {
+			partnerID = session.Interviewee
+		}
+		if id != "" && partnerID != id {
+			continue
+		}
+
+		candidate, ok, err := store.GetRecurser(ctx, partnerID)
+		if err != nil {
+			return botMessages.ReadError
+		}
+		if ok && (id != "" || strings.EqualFold(candidate.Name, name)) {
+			partner = candidate
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Sprintf("I couldn't find %s among your past interview partners! Make sure to @-mention them, e.g. `partner again @**Their Name**`", mention)
+	}
+
+	if !contains(recurser.RematchWith, partner.Id) {
+		recurser.RematchWith = append(recurser.RematchWith, partner.Id)
+		err = store.PutRecurser(ctx, recurser)
+		if err != nil {
+			return botMessages.WriteError
+		}
+	}
+
+	return fmt.Sprintf("You got it! I'll happily pair you with %s again, even if you met recently.", partner.Name)
+}
+
 func subscribe(userID string, userName string, userEmail string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
 	if isSubscribed {
 		return "You're already subscribed!"
 	}
 
 	recurser = newRecurser(userID, userName, userEmail)
-	_, err := client.Collection("recursers").Doc(userID).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	err := store.PutRecurser(ctx, recurser)
+	if err != nil {
+		return botMessages.WriteError
+	}
+
+	err = store.CreateSessionHistory(ctx, userID)
 	if err != nil {
 		return botMessages.WriteError
 	}
 
-	sessions := map[string]interface{}{
-		"sessions": []interface{}{},
+	return botMessages.Subscribe
+}
+
+func unsubscribe(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
 	}
 
-	_, err = client.Collection("soloSessions").Doc(userID).Create(ctx, sessions)
+	err := store.DeleteRecurser(ctx, userID)
 	if err != nil {
 		return botMessages.WriteError
 	}
-
-	_, err = client.Collection("pairingSessions").Doc(userID).Create(ctx, sessions)
+	err = store.DeleteSessionHistory(ctx, userID)
 	if err != nil {
 		return botMessages.WriteError
 	}
 
-	return botMessages.Subscribe
+	return botMessages.Unsubscribe
 }
 
-func unsubscribe(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+// skip skips tomorrow's question, the next few days' or a particular day's.
+// Days are the recurser's, in their time zone.
+func skip(userID string, recurser Recurser, isSubscribed bool, when string, now time.Time, ctx context.Context) string {
 	if isSubscribed == false {
 		return botMessages.NotSubscribed
 	}
 
-	_, err := client.Collection("recursers").Doc(userID).Delete(ctx)
+	today := recurser.localDate(now)
+	var response string
+	if when == "" {
+		recurser.IsSkippingTomorrow = true
+		response = `Tomorrow: skipped. I feel you. **I will not contact you** with a question tomorrow <3`
+	} else if days, err := strconv.Atoi(when); err == nil {
+		date := today
+		for i := 0; i < days; i++ {
+			date = nextDate(date)
+			recurser.SkipDates = addString(recurser.SkipDates, date)
+		}
+		response = fmt.Sprintf("The next %s: skipped. I feel you. **I will not contact you** with a question until then <3", pluralize(days, "day"))
+	} else {
+		if when <= today {
+			return "You can only skip days after today."
+		}
+		recurser.SkipDates = addString(recurser.SkipDates, when)
+		response = fmt.Sprintf("%s: skipped. I feel you. **I will not contact you** with a question that day <3", fmtDate(when))
+	}
+
+	err := store.PutRecurser(ctx, recurser)
 	if err != nil {
 		return botMessages.WriteError
 	}
-	_, err = client.Collection("soloSessions").Doc(userID).Delete(ctx)
+	return response
+}
+
+// validateSkip accepts a number of days or a date. Whether the date is after
+// today depends on the recurser's time zone, so skip checks that.
+func validateSkip(when string) error {
+	if days, err := strconv.Atoi(when); err == nil {
+		if days < 1 || days > 60 {
+			return errors.New("You can skip between 1 and 60 days at a time.")
+		}
+		return nil
+	}
+
+	if _, err := time.Parse(dateLayout, when); err != nil {
+		return fmt.Errorf("%q is neither a number of days nor a date like 2026-11-02.", when)
+	}
+	return nil
+}
+
+func unskip(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	recurser.IsSkippingTomorrow = false
+	recurser.SkipDates = []string{}
+	err := store.PutRecurser(ctx, recurser)
 	if err != nil {
 		return botMessages.WriteError
 	}
-	_, err = client.Collection("pairingSessions").Doc(userID).Delete(ctx)
+
+	return "Tomorrow: unskipped! Heckin *yes*! **I will contact you** with a question tomorrow :)"
+}
+
+// setConfig applies a change to the recurser's config and replies with the
+// lines of their settings that changed
+func setConfig(userID string, recurser Recurser, isSubscribed bool, update func(config *UserConfig), ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	updated := recurser
+	update(&updated.Config)
+	if reflect.DeepEqual(updated.Config, recurser.Config) {
+		return "That's already your setting, so nothing changed!"
+	}
+
+	err := store.UpdateConfig(ctx, userID, updated.Config)
 	if err != nil {
 		return botMessages.WriteError
 	}
 
-	return botMessages.Unsubscribe
+	// some settings only show up in the summary alongside others, e.g. the
+	// order of a pset when there isn't one
+	diff := diffLines(recurser.stringifyUserConfig(), updated.stringifyUserConfig())
+	if len(diff) == 0 {
+		return "Done! That won't change anything until your other settings do."
+	}
+	return fmt.Sprintf("Done! Here's what changed:\n```diff\n%s\n```", strings.Join(diff, "\n"))
+}
+
+// weakestShown is how many topics the topics command lists as the weakest
+const weakestShown = 5
+
+func topics(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+	if isSubscribed == false {
+		return botMessages.NotSubscribed
+	}
+
+	response := "You have not selected specific topics to work on, so your questions can be on anything."
+	if len(recurser.Config.Topics) > 0 {
+		response = fmt.Sprintf("You are focusing on these topics: %s", recurser.Config.Topics)
+	}
+
+	if recurser.Config.WeakTopics {
+		now := time.Now()
+		weaknesses := currentWeakness(recurser, store, now, ctx)
+		if len(weaknesses) > weakestShown {
+			weaknesses = weaknesses[:weakestShown]
+		}
+		response += "\n\nYou're getting more questions on your weakest topics. Right now those are:\n"
+		for _, w := range weaknesses {
+			response += fmt.Sprintf("* %s: %s\n", w.topic, fmtWeakness(w, now))
+		}
+		response = strings.TrimRight(response, "\n")
+	} else {
+		response += "\n\nUse `set weak-topics on` to get more questions on the ones you struggle with."
+	}
+	return response + fmt.Sprintf("\n\nUse `topics add <topic>` or `topics remove <topic>` with any of: %s", strings.Join(topicOptions, ", "))
 }
 
-func skip(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+func addTopic(userID string, recurser Recurser, isSubscribed bool, topic string, ctx context.Context) string {
 	if isSubscribed == false {
 		return botMessages.NotSubscribed
 	}
-	recurser.IsSkippingTomorrow = true
-	_, err := client.Collection("recursers").Doc(userID).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	if contains(recurser.Config.Topics, topic) {
+		return fmt.Sprintf("You're already focusing on %s!", topic)
+	}
+
+	recurser.Config.Topics = append(recurser.Config.Topics, topic)
+	err := store.UpdateConfig(ctx, userID, recurser.Config)
 	if err != nil {
 		return botMessages.WriteError
 	}
 
-	return `Tomorrow: skipped. I feel you. **I will not contact you** with a question tomorrow <3`
+	return fmt.Sprintf("Added %s! You are focusing on these topics: %s", topic, recurser.Config.Topics)
 }
 
-func unskip(userID string, recurser Recurser, isSubscribed bool, ctx context.Context) string {
+func removeTopic(userID string, recurser Recurser, isSubscribed bool, topic string, ctx context.Context) string {
 	if isSubscribed == false {
 		return botMessages.NotSubscribed
 	}
+	if !contains(recurser.Config.Topics, topic) {
+		return fmt.Sprintf("You aren't focusing on %s!", topic)
+	}
 
-	recurser.IsSkippingTomorrow = false
-	_, err := client.Collection("recursers").Doc(userID).Set(ctx, structs.Map(recurser), firestore.MergeAll)
+	recurser.Config.Topics = removeString(recurser.Config.Topics, topic)
+	err := store.UpdateConfig(ctx, userID, recurser.Config)
 	if err != nil {
 		return botMessages.WriteError
 	}
 
-	return "Tomorrow: unskipped! Heckin *yes*! **I will contact you** with a question tomorrow :)"
+	if len(recurser.Config.Topics) == 0 {
+		return fmt.Sprintf("Removed %s! Your questions can be on anything again.", topic)
+	}
+	return fmt.Sprintf("Removed %s! You are focusing on these topics: %s", topic, recurser.Config.Topics)
 }
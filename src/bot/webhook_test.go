@@ -0,0 +1,1633 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDispatch(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	table := []struct {
+		cmd    string
+		userID string
+		want   string
+	}{
+		{
+			cmd:    "schedule",
+			userID: "4",
+			want:   botMessages.NotSubscribed,
+		},
+		{
+			cmd:    "subscribe",
+			userID: "4",
+			want:   botMessages.Subscribe,
+		},
+		{
+			cmd:    "subscribe",
+			userID: "4",
+			want:   "You're already subscribed!",
+		},
+		{
+			cmd:    "cancel",
+			userID: "4",
+			want:   "You are not signed up to pair!",
+		},
+		{
+			cmd:    "skip",
+			userID: "1",
+			want:   `Tomorrow: skipped. I feel you. **I will not contact you** with a question tomorrow <3`,
+		},
+		{
+			cmd:    "unskip",
+			userID: "2",
+			want:   "Tomorrow: unskipped! Heckin *yes*! **I will contact you** with a question tomorrow :)",
+		},
+		{
+			cmd:    "help",
+			userID: "3",
+			want:   helpText(),
+		},
+		{
+			cmd:    "unsubscribe",
+			userID: "3",
+			want:   botMessages.Unsubscribe,
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got, err := handleCommand(ctx, test.cmd, test.userID, "test@example.com", "Test User")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: Expected %q, got %q", name, test.want, got)
+		}
+	}
+
+	if recurser, ok, _ := s.GetRecurser(ctx, "1"); !ok || !recurser.IsSkippingTomorrow {
+		t.Errorf("Expected recurser 1 to be skipping tomorrow")
+	}
+	if recurser, ok, _ := s.GetRecurser(ctx, "2"); !ok || recurser.IsSkippingTomorrow {
+		t.Errorf("Expected recurser 2 to no longer be skipping tomorrow")
+	}
+	if _, ok, _ := s.GetRecurser(ctx, "3"); ok {
+		t.Errorf("Expected recurser 3 to be unsubscribed")
+	}
+	if err := s.AppendSoloSession(ctx, "4", SoloSession{Question: 1}); err != nil {
+		t.Errorf("Expected subscribing to create a session history: %v", err)
+	}
+}
+
+func TestParseCmd(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(dateLayout)
+	yesterday := time.Now().AddDate(0, 0, -1).Format(dateLayout)
+
+	table := []struct {
+		cmd      string
+		wantPath string
+		wantArgs map[string][]string
+		wantErr  string
+	}{
+		{
+			cmd:      "Subscribe",
+			wantPath: "subscribe",
+			wantArgs: map[string][]string{},
+		},
+		{
+			cmd:      "pair",
+			wantPath: "schedule",
+			wantArgs: map[string][]string{},
+		},
+		{
+			cmd:      "skip",
+			wantPath: "skip",
+			wantArgs: map[string][]string{},
+		},
+		{
+			cmd:      "skip 3",
+			wantPath: "skip",
+			wantArgs: map[string][]string{"n days|YYYY-MM-DD": {"3"}},
+		},
+		{
+			cmd:      "skip " + tomorrow,
+			wantPath: "skip",
+			wantArgs: map[string][]string{"n days|YYYY-MM-DD": {tomorrow}},
+		},
+		{
+			// whether it's after today is up to the recurser's time zone
+			cmd:      "skip " + yesterday,
+			wantPath: "skip",
+			wantArgs: map[string][]string{"n days|YYYY-MM-DD": {yesterday}},
+		},
+		{
+			cmd:      "skip 0",
+			wantPath: "skip",
+			wantErr:  "You can skip between 1 and 60 days at a time.\nUsage: `skip [<n days|YYYY-MM-DD>]`",
+		},
+		{
+			cmd:      "skip soon",
+			wantPath: "skip",
+			wantErr:  "\"soon\" is neither a number of days nor a date like 2026-11-02.\nUsage: `skip [<n days|YYYY-MM-DD>]`",
+		},
+		{
+			cmd:      "skip 3 days",
+			wantPath: "skip",
+			wantErr:  "I didn't expect \"days\".\nUsage: `skip [<n days|YYYY-MM-DD>]`",
+		},
+		{
+			cmd:      "pause 5 days pairing",
+			wantPath: "pause",
+			wantArgs: map[string][]string{"n": {"5"}, "days": {"days"}, "pairing": {"pairing"}},
+		},
+		{
+			cmd:      "pause 0 days",
+			wantPath: "pause",
+			wantErr:  "You can pause for between 1 and 365 days at a time.\nUsage: `pause <n> <days|day> [<pairing>]`",
+		},
+		{
+			cmd:      "pause until " + tomorrow,
+			wantPath: "until",
+			wantArgs: map[string][]string{"YYYY-MM-DD": {tomorrow}},
+		},
+		{
+			cmd:      "pause until yesterday",
+			wantPath: "until",
+			wantErr:  "\"yesterday\" isn't a date like 2026-11-02.\nUsage: `pause until <YYYY-MM-DD> [<pairing>]`",
+		},
+		{
+			cmd:      "set difficulty easy MEDIUM",
+			wantPath: "difficulty",
+			wantArgs: map[string][]string{"difficulty": {"easy", "medium"}},
+		},
+		{
+			cmd:      "set difficulty",
+			wantPath: "difficulty",
+			wantErr:  "I need to know the difficulty.\nUsage: `set difficulty <easy|medium|hard>...`",
+		},
+		{
+			cmd:      "set difficulty easy impossible",
+			wantPath: "difficulty",
+			wantErr:  "\"impossible\" isn't a valid difficulty. Try one of: easy, medium, hard\nUsage: `set difficulty <easy|medium|hard>...`",
+		},
+		{
+			cmd:      "partner",
+			wantPath: "partner",
+			wantErr:  "Did you mean `partner again`?",
+		},
+		{
+			cmd:      "topics",
+			wantPath: "topics",
+			wantArgs: map[string][]string{},
+		},
+		{
+			cmd:      "topics add graph",
+			wantPath: "add",
+			wantArgs: map[string][]string{"topic": {"graph"}},
+		},
+		{
+			cmd:      "topic rm Graph",
+			wantPath: "remove",
+			wantArgs: map[string][]string{"topic": {"graph"}},
+		},
+		{
+			cmd:      "topics add",
+			wantPath: "add",
+			wantErr:  "I need to know the topic.\nUsage: `topics add <topic>`",
+		},
+		{
+			cmd:      "partner again @**Grace Hopper**",
+			wantPath: "again",
+			wantArgs: map[string][]string{"@mention": {"@**Grace", "Hopper**"}},
+		},
+		{
+			cmd:      "make me a sandwich",
+			wantPath: "",
+			wantErr:  "the user-issued command wasn't valid",
+		},
+		{
+			cmd:      "   ",
+			wantPath: "",
+			wantErr:  "the user-issued command was blank",
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		cmd, args, err := parseCmd(test.cmd)
+
+		var gotPath string
+		if cmd != nil {
+			gotPath = cmd.name
+		}
+		if gotPath != test.wantPath {
+			t.Errorf("%s: Expected command %q, got %q", name, test.wantPath, gotPath)
+		}
+
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != test.wantErr {
+			t.Errorf("%s: Expected error %q, got %q", name, test.wantErr, gotErr)
+		}
+		if test.wantErr == "" && !reflect.DeepEqual(args, test.wantArgs) {
+			t.Errorf("%s: Expected %v, got %v", name, test.wantArgs, args)
+		}
+	}
+}
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	help := helpText()
+	for _, usage := range []string{
+		"`subscribe`", "`schedule`", "(or `pair`)", "`partner again <@mention>...`",
+		"`skip [<n days|YYYY-MM-DD>]`", "`set difficulty <easy|medium|hard>...`",
+		"`topics add <topic>`", "`topics remove <topic>`", "(or `rm`)", "`help`",
+	} {
+		if !strings.Contains(help, usage) {
+			t.Errorf("Expected help to mention %s, got %q", usage, help)
+		}
+	}
+	if strings.Contains(help, "* `partner`") || strings.Contains(help, "* `set`") {
+		t.Errorf("Expected commands without a handler to be left out of help, got %q", help)
+	}
+}
+
+func TestSkipAndTopicCommands(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC() // Alan's time zone
+	dates := func(days ...int) []string {
+		var list []string
+		for _, day := range days {
+			list = append(list, now.AddDate(0, 0, day).Format(dateLayout))
+		}
+		return list
+	}
+	inAWeek := now.AddDate(0, 0, 7)
+
+	steps := []struct {
+		send string
+		want string
+	}{
+		{
+			send: "skip 2",
+			want: "The next 2 days: skipped. I feel you. **I will not contact you** with a question until then <3",
+		},
+		{
+			send: "skip " + inAWeek.Format(dateLayout),
+			want: inAWeek.Format("Monday, January 2") + ": skipped. I feel you. **I will not contact you** with a question that day <3",
+		},
+		{
+			send: "set difficulty hard Easy",
+			want: "Done! Here's what changed:\n```diff\n" +
+				"- You will receive questions of this difficulty: [easy]\n" +
+				"+ You will receive questions of this difficulty: [easy hard]\n```",
+		},
+		{
+			send: "topics add graph",
+			want: "Added graph! You are focusing on these topics: [array graph]",
+		},
+		{
+			send: "topics add Graph",
+			want: "You're already focusing on graph!",
+		},
+		{
+			send: "topics rm graph",
+			want: "Removed graph! You are focusing on these topics: [array]",
+		},
+		{
+			send: "topics remove graph",
+			want: "You aren't focusing on graph!",
+		},
+	}
+
+	for i, step := range steps {
+		got, _ := handleCommand(ctx, step.send, "3", "alan@example.com", "Alan Turing")
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+		if i == 1 {
+			alan, _, _ := s.GetRecurser(ctx, "3")
+			if want := dates(1, 2, 7); !reflect.DeepEqual(alan.SkipDates, want) {
+				t.Errorf("Expected Alan to skip %v, got %v", want, alan.SkipDates)
+			}
+		}
+	}
+
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	if !reflect.DeepEqual(alan.Config.SoloDifficulty, []string{"easy", "hard"}) {
+		t.Errorf("Expected Alan's difficulties to be set, got %v", alan.Config.SoloDifficulty)
+	}
+
+	handleCommand(ctx, "unskip", "3", "alan@example.com", "Alan Turing")
+	if alan, _, _ := s.GetRecurser(ctx, "3"); len(alan.SkipDates) != 0 {
+		t.Errorf("Expected unskip to clear Alan's skip dates, got %v", alan.SkipDates)
+	}
+}
+
+func TestSkipAndPauseInTimeZone(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	// it's still the 18th on the server but already the 19th for Alan
+	now := time.Date(2026, time.October, 18, 23, 30, 0, 0, time.UTC)
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	alan.Config.Timezone = "Pacific/Kiritimati"
+	s.PutRecurser(ctx, alan)
+
+	if got := skip("3", alan, true, "2026-10-19", now, ctx); got != "You can only skip days after today." {
+		t.Errorf("Expected Alan not to be able to skip his today, got %q", got)
+	}
+	skip("3", alan, true, "2", now, ctx)
+	if alan, _, _ = s.GetRecurser(ctx, "3"); !reflect.DeepEqual(alan.SkipDates, []string{"2026-10-20", "2026-10-21"}) {
+		t.Errorf("Expected Alan to skip the two days after his today, got %v", alan.SkipDates)
+	}
+
+	if got := pause("3", alan, true, "2026-10-19", false, now, ctx); got != "You can only pause until a day after today." {
+		t.Errorf("Expected Alan not to be able to pause until his today, got %q", got)
+	}
+	if got := pause("3", alan, true, "2027-10-20", false, now, ctx); got != "You can pause for up to 365 days at a time." {
+		t.Errorf("Expected Alan not to be able to pause for over a year of his days, got %q", got)
+	}
+	if got := pause("3", alan, true, "2027-10-19", false, now, ctx); !strings.HasPrefix(got, "Paused!") {
+		t.Errorf("Expected Alan to be able to pause for a year of his days, got %q", got)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	today := time.Now().UTC()
+	date := func(days int) string {
+		return today.AddDate(0, 0, days).Format(dateLayout)
+	}
+	day := func(days int) string {
+		return today.AddDate(0, 0, days).Format("Monday, January 2")
+	}
+
+	steps := []struct {
+		send string
+		want string
+	}{
+		{
+			send: "resume",
+			want: "You aren't paused, so there's nothing to resume!",
+		},
+		{
+			send: "pause 3 days",
+			want: "Paused! Enjoy the break :) **I will not send you** solo questions until " + day(3) + ". Use `resume` to come back early.",
+		},
+		{
+			// the new pause runs on from the one before it
+			send: "pause until " + date(2) + " pairing",
+			want: "Paused! Enjoy the break :) **I will not send you** solo questions or pairing matches until " + day(2) + ". Use `resume` to come back early.",
+		},
+		{
+			send: "resume",
+			want: "Welcome back! Your pauses are cancelled and **I will contact you** as usual :)",
+		},
+	}
+
+	for i, step := range steps {
+		got, _ := handleCommand(ctx, step.send, "3", "alan@example.com", "Alan Turing")
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+		if i != 2 {
+			continue
+		}
+
+		alan, _, _ := s.GetRecurser(ctx, "3")
+		want := []Pause{{Start: date(0), End: date(2)}, {Start: date(0), End: date(1), Pairing: true}}
+		if !reflect.DeepEqual(alan.Pauses, want) {
+			t.Errorf("Expected Alan's pauses to be %v, got %v", want, alan.Pauses)
+		}
+		if !alan.isPaused(today, false) || !alan.isPaused(today, true) || alan.isPaused(today.AddDate(0, 0, 3), false) {
+			t.Errorf("Expected Alan to be paused for 3 days, got %v", alan.Pauses)
+		}
+		config := alan.stringifyUserConfig()
+		for _, line := range []string{
+			"You are paused, so your solo sessions resume on " + day(3) + ".",
+			"You are paused from pairing until " + day(2) + ".",
+		} {
+			if !strings.Contains(config, line) {
+				t.Errorf("Expected Alan's config to include %q, got %q", line, config)
+			}
+		}
+	}
+
+	if alan, _, _ := s.GetRecurser(ctx, "3"); len(alan.Pauses) != 0 || alan.isPaused(today, false) {
+		t.Errorf("Expected resume to clear Alan's pauses, got %v", alan.Pauses)
+	}
+}
+
+func TestSelectQuestion(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"1", "2", "3"} {
+		recurser, _, _ := s.GetRecurser(ctx, id)
+		for i := 0; i < 20; i++ {
+			question, _ := selectQuestion(recurser, false, s, time.Now(), ctx)
+			if question == nil {
+				t.Fatalf("Expected a question for recurser %s", id)
+			}
+
+			config := recurser.Config
+			if !contains(config.SoloDifficulty, question.Difficulty) {
+				t.Errorf("Recurser %s: %v does not match difficulties %v", id, question.Id, config.SoloDifficulty)
+			}
+			if len(config.Topics) > 0 && !hasAnyTopic(*question, config.Topics) {
+				t.Errorf("Recurser %s: %v does not match topics %v", id, question.Id, config.Topics)
+			}
+			if config.ProblemSet != "random" && !contains(question.Psets, config.ProblemSet) {
+				t.Errorf("Recurser %s: %v is not in pset %s", id, question.Id, config.ProblemSet)
+			}
+		}
+	}
+}
+
+func TestSelectQuestionAvoidsRepeats(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	ada, _, _ := s.GetRecurser(ctx, "1")
+
+	// Ada's config fits questions 1, 3, 26 and 104
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 1, TimeStamp: now.AddDate(0, 0, -4)})
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 26, TimeStamp: now.AddDate(0, 0, -3)})
+	s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "2", Interviewee: "1", Question: 3, TimeStamp: now.AddDate(0, 0, -5)})
+
+	for i := 0; i < 20; i++ {
+		question, repeat := selectQuestion(ada, false, s, time.Now(), ctx)
+		if question == nil || question.Id != 104 || repeat {
+			t.Fatalf("Expected the only question Ada hasn't had, got %v (repeat: %v)", question, repeat)
+		}
+	}
+
+	// once she's had everything she gets the one she had longest ago
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 104, TimeStamp: now})
+	question, repeat := selectQuestion(ada, false, s, time.Now(), ctx)
+	if question == nil || question.Id != 3 || !repeat {
+		t.Errorf("Expected Ada to repeat question 3, got %v (repeat: %v)", question, repeat)
+	}
+}
+
+func TestSelectQuestionInOrder(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Config.Sequential = true
+	s.PutRecurser(ctx, ada)
+
+	// topInterview lists 1, 26, 3, 104, 297 and 4, and Ada gets them in that
+	// order whatever her difficulties. Interviews don't move her along, nor do
+	// they follow the pset.
+	for _, want := range []int{1, 26, 3, 104, 297, 4} {
+		question, repeat := selectQuestion(ada, false, s, time.Now(), ctx)
+		if question == nil || question.Id != want || repeat {
+			t.Fatalf("Expected question %v next, got %v (repeat: %v)", want, question, repeat)
+		}
+		if interview, _ := selectQuestion(ada, true, s, time.Now(), ctx); interview == nil || !contains(ada.Config.PairingDifficulty, interview.Difficulty) {
+			t.Fatalf("Expected an interview question at Ada's pairing difficulty, got %v", interview)
+		}
+		s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "2", Interviewee: "1", Question: want, TimeStamp: time.Now()})
+		if next, _ := selectQuestion(ada, false, s, time.Now(), ctx); next == nil || next.Id != want {
+			t.Fatalf("Expected an interview on %v not to move Ada along, got %v", want, next)
+		}
+		s.AppendSoloSession(ctx, "1", SoloSession{Question: question.Id, TimeStamp: time.Now()})
+	}
+
+	// once the pset is done it's back to random questions that fit her config
+	question, _ := selectQuestion(ada, false, s, time.Now(), ctx)
+	if question == nil || !contains(ada.Config.SoloDifficulty, question.Difficulty) {
+		t.Errorf("Expected a random easy or medium question after finishing the pset, got %v", question)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	ada, _, _ := s.GetRecurser(ctx, "1")
+	ada.Config.Sequential = true
+	s.PutRecurser(ctx, ada)
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 1, TimeStamp: time.Now()})
+	// only solo questions count, not the ones from Ada's interviews
+	s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "2", Interviewee: "1", Question: 26, TimeStamp: time.Now()})
+	s.AppendPairingSession(ctx, "2", PairingSession{Interviewer: "1", Interviewee: "2", Question: 4, TimeStamp: time.Now()})
+
+	want := "You've done 1 of the 6 questions in the topInterview pset (16%) and are working through it in order. " +
+		"Up next is [Remove Duplicates from Sorted Array](https://leetcode.com/problems/remove-duplicates-from-sorted-array).\n\n" +
+		"**By topic:**\n" +
+		"* array: 1/3\n" +
+		"* binarySearch: 0/1\n" +
+		"* depth-firstSearch: 0/1\n" +
+		"* design: 0/1\n" +
+		"* divideAndConquer: 0/1\n" +
+		"* hashTable: 1/2\n" +
+		"* recursion: 0/1\n" +
+		"* slidingWindow: 0/1\n" +
+		"* string: 0/1\n" +
+		"* tree: 0/2\n" +
+		"* twoPointers: 0/2"
+	if got, _ := handleCommand(ctx, "progress", "1", "ada@example.com", "Ada Lovelace"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	want = "You're getting questions from all over rather than from a problem set, so there's no progress to track! " +
+		"Use `set pset topInterview` or `set pset top100Liked` to work through one."
+	if got, _ := handleCommand(ctx, "progress", "2", "grace@example.com", "Grace Hopper"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestAdaptMix(t *testing.T) {
+	questions := map[int]Question{
+		1: {Id: 1, Difficulty: "easy"},
+		3: {Id: 3, Difficulty: "medium"},
+		4: {Id: 4, Difficulty: "hard"},
+	}
+	config := UserConfig{SoloDifficulty: []string{"easy", "medium"}}
+	times := func(n int, session SoloSession) []SoloSession {
+		var sessions []SoloSession
+		for i := 0; i < n; i++ {
+			sessions = append(sessions, session)
+		}
+		return sessions
+	}
+
+	table := []struct {
+		sessions []SoloSession
+		level    float64
+		weights  map[string]float64
+	}{
+		{
+			// not enough to go on, so it's the config's difficulties
+			sessions: append(times(2, SoloSession{Question: 4, Rating: 5}), SoloSession{Question: 1}),
+			level:    0,
+			weights:  map[string]float64{"easy": 0.5, "medium": 0.5},
+		},
+		{
+			sessions: times(3, SoloSession{Question: 1, Outcome: outcomeSolved, Rating: 3}),
+			level:    0,
+			weights:  map[string]float64{"easy": 1},
+		},
+		{
+			sessions: times(3, SoloSession{Question: 1, Rating: 5}),
+			level:    0.5,
+			weights:  map[string]float64{"easy": 0.5, "medium": 0.5},
+		},
+		{
+			// solving without a rating counts as finding it easy
+			sessions: times(4, SoloSession{Question: 3, Outcome: outcomeSolved}),
+			level:    1.5,
+			weights:  map[string]float64{"medium": 0.5, "hard": 0.5},
+		},
+		{
+			sessions: times(3, SoloSession{Question: 4, Outcome: outcomeGaveUp}),
+			level:    1.5,
+			weights:  map[string]float64{"medium": 0.5, "hard": 0.5},
+		},
+		{
+			// only the latest finished questions count
+			sessions: append(times(adaptiveWindow, SoloSession{Question: 1, Rating: 1}), times(adaptiveWindow, SoloSession{Question: 4, Rating: 5})...),
+			level:    2,
+			weights:  map[string]float64{"hard": 1},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := adaptMix(config, test.sessions, questions)
+		if got.level != test.level || !reflect.DeepEqual(got.weights, test.weights) {
+			t.Errorf("%s: Expected level %v with %v, got %v with %v", name, test.level, test.weights, got.level, got.weights)
+		}
+	}
+}
+
+func TestAdaptiveDifficulty(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	handleCommand(ctx, "set adaptive on", "1", "ada@example.com", "Ada Lovelace")
+	for i := 0; i < 3; i++ {
+		s.AppendSoloSession(ctx, "1", SoloSession{Question: 3, TimeStamp: time.Now(), Outcome: outcomeSolved, Rating: 4})
+	}
+	ada, _, _ := s.GetRecurser(ctx, "1")
+
+	// Ada's config is easy and medium but she's found medium questions easy
+	for i := 0; i < 20; i++ {
+		question, _ := selectQuestion(ada, false, s, time.Now(), ctx)
+		if question == nil || question.Difficulty == "easy" {
+			t.Fatalf("Expected a medium or hard question, got %v", question)
+		}
+	}
+
+	want := "Adaptive difficulty is on. Going by your last 3 finished questions, you're at level 1.5 (0 is easy, 1 medium and 2 hard).\n\n" +
+		"**Your mix:**\n* easy: 0%\n* medium: 50%\n* hard: 50%\n\n" +
+		"**Why:**\n" + strings.Repeat("* [Longest Substring Without Repeating Characters](https://leetcode.com/problems/longest-substring-without-repeating-characters) (medium): solved, rated 4/5, nudging you up\n", 3)
+	if got, _ := handleCommand(ctx, "mix", "1", "ada@example.com", "Ada Lovelace"); got != strings.TrimSuffix(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// interview questions stay within her pairing difficulties
+	ada.Config.PairingDifficulty = []string{"easy"}
+	for i := 0; i < 20; i++ {
+		question, _ := selectQuestion(ada, true, s, time.Now(), ctx)
+		if question == nil || question.Difficulty != "easy" {
+			t.Fatalf("Expected an easy interview question, got %v", question)
+		}
+	}
+
+	handleCommand(ctx, "set adaptive off", "1", "ada@example.com", "Ada Lovelace")
+	want = "Your questions are picked evenly from the difficulties in your config. Use `set adaptive on` to have them follow how you're doing instead.\n\n" +
+		"**Your mix:**\n* easy: 50%\n* medium: 50%\n* hard: 0%"
+	if got, _ := handleCommand(ctx, "difficulty", "1", "ada@example.com", "Ada Lovelace"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestMeasureWeakness(t *testing.T) {
+	now := time.Date(2020, time.October, 20, 9, 0, 0, 0, time.UTC)
+	questions := map[int]Question{
+		1:   {Id: 1, Tags: []string{"array", "hashTable"}},
+		104: {Id: 104, Tags: []string{"tree", "depth-firstSearch", "recursion"}},
+		133: {Id: 133, Tags: []string{"depth-firstSearch", "breadth-firstSearch", "graph"}},
+	}
+	topics := []string{"array", "tree", "graph"}
+
+	table := []struct {
+		sessions []SoloSession
+		want     map[string]float64
+		order    []string
+	}{
+		{
+			// nothing to go on, so every topic weighs the same
+			sessions: nil,
+			want:     map[string]float64{"array": 3, "tree": 3, "graph": 3},
+			order:    []string{"array", "tree", "graph"},
+		},
+		{
+			// array went well and was quick, tree was given up on once and
+			// was slow to solve, graph hasn't come up
+			sessions: []SoloSession{
+				{Question: 1, TimeStamp: now.AddDate(0, 0, -7), Outcome: outcomeSolved, Minutes: 20},
+				{Question: 104, TimeStamp: now.AddDate(0, 0, -2), Outcome: outcomeGaveUp},
+				{Question: 104, TimeStamp: now, Outcome: outcomeSolved, Minutes: 60},
+			},
+			want:  map[string]float64{"array": 1.5, "tree": 2.5, "graph": 3},
+			order: []string{"graph", "tree", "array"},
+		},
+		{
+			// an unfinished attempt counts as half solved
+			sessions: []SoloSession{{Question: 133, TimeStamp: now}},
+			want:     map[string]float64{"array": 3, "tree": 3, "graph": 2},
+			order:    []string{"array", "tree", "graph"},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := measureWeakness(topics, test.sessions, questions, now)
+		var order []string
+		weights := make(map[string]float64)
+		for _, w := range got {
+			order = append(order, w.topic)
+			weights[w.topic] = w.weight
+		}
+		if !reflect.DeepEqual(order, test.order) || !reflect.DeepEqual(weights, test.want) {
+			t.Errorf("%s: Expected %v with %v, got %v with %v", name, test.order, test.want, order, weights)
+		}
+	}
+}
+
+func TestWeakTopics(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	got, _ := handleCommand(ctx, "set weak-topics on", "2", "grace@example.com", "Grace Hopper")
+	want := "Done! Here's what changed:\n```diff\n- You are focusing on these topics: [tree design]\n+ You are focusing on your weakest of these topics: [tree design]\n```"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	s.AppendSoloSession(ctx, "2", SoloSession{Question: 297, TimeStamp: time.Now().AddDate(0, 0, -3), Outcome: outcomeGaveUp})
+	grace, _, _ := s.GetRecurser(ctx, "2")
+	for i := 0; i < 10; i++ {
+		question, _ := selectQuestion(grace, false, s, time.Now(), ctx)
+		if question == nil || !hasAnyTopic(*question, grace.Config.Topics) {
+			t.Fatalf("Expected a question on %v, got %v", grace.Config.Topics, question)
+		}
+	}
+
+	// interviews don't lean towards weak topics, so with no topics to focus on
+	// any will do
+	grace.Config.Topics = nil
+	r := rand.New(rand.NewSource(1))
+	if topic := selectTopic(grace, false, r, s, time.Now(), ctx); topic == "" {
+		t.Errorf("Expected a weak topic for Grace's solo question")
+	}
+	if topic := selectTopic(grace, true, r, s, time.Now(), ctx); topic != "" {
+		t.Errorf("Expected any topic for Grace's interview question, got %q", topic)
+	}
+
+	want = "You are focusing on these topics: [tree design]\n\n" +
+		"You're getting more questions on your weakest topics. Right now those are:\n" +
+		"* tree: 0 of 1 went well, last seen 3 days ago\n" +
+		"* design: 0 of 1 went well, last seen 3 days ago\n\n" +
+		"Use `topics add <topic>` or `topics remove <topic>` with any of: " + strings.Join(topicOptions, ", ")
+	if got, _ := handleCommand(ctx, "topics", "2", "grace@example.com", "Grace Hopper"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func hasAnyTopic(question Question, topics []string) bool {
+	for _, topic := range topics {
+		if contains(question.Tags, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWebhookConversation(t *testing.T) {
+	newTestStore(t)
+	zulip := newFakeZulip(t)
+	barbara := Recurser{Id: "5", Name: "Barbara Liskov", Email: "barbara@example.com"}
+
+	steps := []struct {
+		send string
+		want string
+	}{
+		{
+			send: "schedule",
+			want: botMessages.NotSubscribed,
+		},
+		{
+			send: "subscribe",
+			want: botMessages.Subscribe,
+		},
+		{
+			send: "  SKIP ",
+			want: `Tomorrow: skipped. I feel you. **I will not contact you** with a question tomorrow <3`,
+		},
+		{
+			send: "unskip",
+			want: "Tomorrow: unskipped! Heckin *yes*! **I will contact you** with a question tomorrow :)",
+		},
+		{
+			send: "schedule",
+			want: "You're all set for a mock interview session! You'll be contacted shortly with all the pertinent details!\n\n" +
+				"Of the 2 other Recursers in the queue, 2 are a valid match!\n" +
+				"You joined the queue less than a day ago.",
+		},
+		{
+			send: "cancel",
+			want: `Tomorrow: cancelled. I feel you. **I will not match you** for pairing tomorrow <3`,
+		},
+		{
+			send: "make me a sandwich",
+			want: helpText(),
+		},
+		{
+			send: "unsubscribe",
+			want: botMessages.Unsubscribe,
+		},
+	}
+
+	for i, step := range steps {
+		got := zulip.sendToBot(barbara, step.send)
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+	}
+
+	conversation := zulip.conversation(barbara.Email)
+	if len(conversation) != 2*len(steps) {
+		t.Fatalf("Expected %v messages in the conversation, got %v", 2*len(steps), len(conversation))
+	}
+	for i, step := range steps {
+		if conversation[2*i].Content != step.send || conversation[2*i+1].From != botEmailAddress {
+			t.Errorf("Step %v: conversation out of order: %v", i, conversation[2*i:2*i+2])
+		}
+	}
+}
+
+func TestPartnerAgain(t *testing.T) {
+	s := newTestStore(t)
+	zulip := newFakeZulip(t)
+	ctx := context.Background()
+	ada, _, _ := s.GetRecurser(ctx, "1")
+
+	notFound := "I couldn't find Grace Hopper among your past interview partners! Make sure to @-mention them, e.g. `partner again @**Their Name**`"
+	if got := zulip.sendToBot(ada, "partner again @**Grace Hopper**"); got != notFound {
+		t.Errorf("Expected %q before Ada and Grace ever paired, got %q", notFound, got)
+	}
+
+	s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "2", Interviewee: "1", Question: 1, TimeStamp: time.Now()})
+
+	steps := []struct {
+		send string
+		want string
+	}{
+		{
+			send: "partner again @**Grace Hopper**",
+			want: "You got it! I'll happily pair you with Grace Hopper again, even if you met recently.",
+		},
+		{
+			send: "partner   again @**Grace Hopper|2**",
+			want: "You got it! I'll happily pair you with Grace Hopper again, even if you met recently.",
+		},
+		{
+			send: "partner again @**Alan Turing**",
+			want: "I couldn't find Alan Turing among your past interview partners! Make sure to @-mention them, e.g. `partner again @**Their Name**`",
+		},
+		{
+			send: "partner",
+			want: "Did you mean `partner again`?",
+		},
+	}
+
+	for i, step := range steps {
+		got := zulip.sendToBot(ada, step.send)
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+	}
+
+	if got, _, _ := s.GetRecurser(ctx, "1"); !reflect.DeepEqual(got.RematchWith, []string{"2"}) {
+		t.Errorf("Expected Ada to want a rematch with Grace only once, got %v", got.RematchWith)
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	changed := func(lines ...string) string {
+		return "Done! Here's what changed:\n```diff\n" + strings.Join(lines, "\n") + "\n```"
+	}
+
+	steps := []struct {
+		send string
+		want string
+	}{
+		{
+			send: "set experience hard",
+			want: changed("- Your experience level is easy.", "+ Your experience level is hard."),
+		},
+		{
+			send: "set problemset random",
+			want: changed("- You are working through the top100Liked pset.", "+ You are working through the random pset."),
+		},
+		{
+			send: "set topics trie graph",
+			want: changed("- You are focusing on these topics: [array]", "+ You are focusing on these topics: [graph trie]"),
+		},
+		{
+			send: "set topics",
+			want: changed("- You are focusing on these topics: [graph trie]", "+ You have not selected specific topics to work on."),
+		},
+		{
+			send: "set days fri mon",
+			want: changed("- You have solo sessions scheduled for these days: [mon wed fri]", "+ You have solo sessions scheduled for these days: [mon fri]"),
+		},
+		{
+			send: "set pairing-difficulty medium",
+			want: changed("- You will be interviewed on questions of this difficulty: [easy]", "+ You will be interviewed on questions of this difficulty: [medium]"),
+		},
+		{
+			send: "set env replit",
+			want: changed("- Your preferred environment is googleDocs.", "+ Your preferred environment is replit."),
+		},
+		{
+			send: "set manual no",
+			want: changed("- You will be choosing your own questions for your pairing sessions.", "+ You will receive random questions for your pairing sessions."),
+		},
+		{
+			send: "set comments  I'd like to practice   graphs!",
+			want: changed("- Your comments for your partners: N/A", "+ Your comments for your partners: I'd like to practice graphs!"),
+		},
+		{
+			send: "set comments " + strings.Repeat("graphs ", 100),
+			want: "Please keep your comments under 500 characters.\nUsage: `set comments <comments>...`",
+		},
+		{
+			send: "set time 8am",
+			want: changed("- Your solo questions go out at 11:00, UTC time.", "+ Your solo questions go out at 08:00, UTC time."),
+		},
+		{
+			send: "set tz America/New_York",
+			want: changed("- Your solo questions go out at 08:00, UTC time.", "+ Your solo questions go out at 08:00, America/New_York time."),
+		},
+		{
+			send: "set time 8:30",
+			want: "I only send questions on the hour, so \"8:30\" won't work. Try 08:00 instead.\nUsage: `set time <time>`",
+		},
+		{
+			send: "set timezone Mars/Olympus",
+			want: "\"Mars/Olympus\" isn't a time zone I know. Try a name like America/New_York or Europe/London.\nUsage: `set timezone <time zone>`",
+		},
+		{
+			send: "set env replit",
+			want: "That's already your setting, so nothing changed!",
+		},
+		{
+			send: "set order sequential",
+			want: "Done! That won't change anything until your other settings do.",
+		},
+		{
+			send: "set env vim",
+			want: "\"vim\" isn't a valid environment. Try one of: leetcode, replit, googleDocs\nUsage: `set environment <leetcode|replit|googleDocs>`",
+		},
+		{
+			send: "set days someday",
+			want: "\"someday\" isn't a valid day. Try one of: sun, mon, tue, wed, thu, fri, sat\nUsage: `set days [<day>...]`",
+		},
+		{
+			send: "set manual maybe",
+			want: "\"maybe\" isn't a valid choice. Try one of: yes, no\nUsage: `set manual-question <yes|no>`",
+		},
+	}
+
+	for i, step := range steps {
+		got, _ := handleCommand(ctx, step.send, "3", "alan@example.com", "Alan Turing")
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+	}
+
+	alan, _, _ := s.GetRecurser(ctx, "3")
+	want := UserConfig{
+		Comments:          "I'd like to practice graphs!",
+		Environment:       "replit",
+		Experience:        "hard",
+		ProblemSet:        "random",
+		Sequential:        true,
+		Topics:            []string{},
+		SoloDays:          []string{"mon", "fri"},
+		SoloTime:          "08:00",
+		Timezone:          "America/New_York",
+		SoloDifficulty:    alan.Config.SoloDifficulty,
+		PairingDifficulty: []string{"medium"},
+		ManualQuestion:    false,
+	}
+	if !reflect.DeepEqual(alan.Config, want) {
+		t.Errorf("Expected %+v, got %+v", want, alan.Config)
+	}
+
+	if got, _ := handleCommand(ctx, "set", "3", "alan@example.com", "Alan Turing"); !strings.HasPrefix(got, "Did you mean one of these?\n* `set experience <easy|medium|hard>`") {
+		t.Errorf("Expected set on its own to list what can be set, got %q", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	table := []struct {
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			before: "a\nb\nc\n",
+			after:  "a\nb\nc\n",
+			want:   nil,
+		},
+		{
+			before: "a\nb\nc\n",
+			after:  "a\nB\nc\n",
+			want:   []string{"- b", "+ B"},
+		},
+		{
+			before: "a\nc\n",
+			after:  "a\nb\nc\n",
+			want:   []string{"+ b"},
+		},
+		{
+			before: "a\nb\nc\n",
+			after:  "b\n",
+			want:   []string{"- a", "- c"},
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := diffLines(test.before, test.after)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	want := "You haven't gotten a question from me yet, so there's nothing to rate!"
+	if got, _ := handleCommand(ctx, "rate 4", "1", "ada@example.com", "Ada Lovelace"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	sent := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 1, TimeStamp: sent.AddDate(0, 0, -1), Rating: 5})
+	s.AppendSoloSession(ctx, "1", SoloSession{Question: 1, TimeStamp: sent, Review: true})
+
+	// a second good rating in a row pushes the next review out by six days
+	want = "Thanks for rating it 4/5! I'll bring that question back for review around Thursday, October 22."
+	if got, _ := handleCommand(ctx, "rate 4", "1", "ada@example.com", "Ada Lovelace"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	sessions, _ := s.SoloSessions(ctx, "1")
+	if len(sessions) != 2 || sessions[0].Rating != 5 || sessions[1].Rating != 4 || !sessions[1].Review {
+		t.Errorf("Expected only the latest session to be rated, got %v", sessions)
+	}
+
+	want = "\"6\" isn't a valid rating. Try one of: 1, 2, 3, 4, 5\nUsage: `rate <1|2|3|4|5>`"
+	if got, _ := handleCommand(ctx, "rate 6", "1", "ada@example.com", "Ada Lovelace"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestDoneAndGaveUp(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	sent := time.Date(2026, 10, 12, 7, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		question int // a new question is sent before the step if set
+		send     string
+		want     string
+	}{
+		{
+			send: "done",
+			want: "You haven't gotten a question from me yet, so there's nothing to mark as done!",
+		},
+		{
+			question: 1,
+			send:     "done 25",
+			want:     "Nice work solving it in 25 minutes!\n\nHow did it feel? `rate` it from 1 (couldn't solve it) to 5 (easy).",
+		},
+		{
+			question: 3,
+			send:     "solved",
+			want:     "Nice work solving it! That's 2 solved in a row :fire:\n\nHow did it feel? `rate` it from 1 (couldn't solve it) to 5 (easy).",
+		},
+		{
+			send: "rate 5",
+			want: "Thanks for rating it 5/5! I'll bring that question back for review around Wednesday, October 14.",
+		},
+		{
+			question: 4,
+			send:     "gaveup 60",
+			want:     "No worries, it happens to everyone! Take a look at some solutions and I'll bring it back for another try around Thursday, October 15.",
+		},
+		{
+			question: 26,
+			send:     "done 1 hour",
+			want:     "I didn't expect \"hour\".\nUsage: `done [<minutes>]`",
+		},
+		{
+			send: "done 0",
+			want: "\"0\" isn't a number of minutes between 1 and 600.\nUsage: `done [<minutes>]`",
+		},
+		{
+			send: "done 10",
+			want: "Nice work solving it in 10 minutes!\n\nHow did it feel? `rate` it from 1 (couldn't solve it) to 5 (easy).",
+		},
+		{
+			send: "stats",
+			want: "**Your solo sessions so far:**\n" +
+				"* Questions received: 4\n" +
+				"* Solved: 3, gave up: 1, unreported: 0\n" +
+				"* Solved by difficulty: 2 easy, 1 medium\n" +
+				"* Average time to solve: 17 minutes\n" +
+				"* Current streak: 1 question solved in a row (longest: 2)",
+		},
+	}
+
+	day := 0
+	for i, step := range steps {
+		if step.question != 0 {
+			s.AppendSoloSession(ctx, "1", SoloSession{Question: step.question, TimeStamp: sent.AddDate(0, 0, day)})
+			day++
+		}
+		got, _ := handleCommand(ctx, step.send, "1", "ada@example.com", "Ada Lovelace")
+		if got != step.want {
+			t.Errorf("Step %v (%q): Expected %q, got %q", i, step.send, step.want, got)
+		}
+	}
+
+	sessions, _ := s.SoloSessions(ctx, "1")
+	want := []SoloSession{
+		{Question: 1, TimeStamp: sent, Outcome: outcomeSolved, Minutes: 25},
+		{Question: 3, TimeStamp: sent.AddDate(0, 0, 1), Outcome: outcomeSolved, Rating: 5},
+		{Question: 4, TimeStamp: sent.AddDate(0, 0, 2), Outcome: outcomeGaveUp, Minutes: 60},
+		{Question: 26, TimeStamp: sent.AddDate(0, 0, 3), Outcome: outcomeSolved, Minutes: 10},
+	}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Errorf("Expected %v, got %v", want, sessions)
+	}
+}
+
+func TestComputeSoloStats(t *testing.T) {
+	solved := SoloSession{Outcome: outcomeSolved}
+	gaveUp := SoloSession{Outcome: outcomeGaveUp}
+	unreported := SoloSession{}
+
+	table := []struct {
+		sessions []SoloSession
+		current  int
+		longest  int
+	}{
+		{
+			sessions: nil,
+			current:  0,
+			longest:  0,
+		},
+		{
+			sessions: []SoloSession{solved, solved, gaveUp, solved},
+			current:  1,
+			longest:  2,
+		},
+		{
+			// they may still be working on the latest question
+			sessions: []SoloSession{solved, solved, unreported},
+			current:  2,
+			longest:  2,
+		},
+		{
+			// but one they never got back to breaks the streak
+			sessions: []SoloSession{solved, solved, unreported, solved},
+			current:  1,
+			longest:  2,
+		},
+		{
+			sessions: []SoloSession{solved, solved, solved, gaveUp},
+			current:  0,
+			longest:  3,
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := computeSoloStats(test.sessions, nil)
+		if got.currentStreak != test.current || got.longestStreak != test.longest {
+			t.Errorf("%s: Expected streaks of %v (longest %v), got %v (longest %v)", name, test.current, test.longest, got.currentStreak, got.longestStreak)
+		}
+	}
+}
+
+func TestFmtWaitTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		recurser Recurser
+		want     string
+	}{
+		{
+			recurser: Recurser{},
+			want:     "",
+		},
+		{
+			recurser: Recurser{QueuedAt: now.Add(-3 * time.Hour)},
+			want:     "You joined the queue less than a day ago.",
+		},
+		{
+			recurser: Recurser{QueuedAt: now.AddDate(0, 0, -1)},
+			want:     "You've been waiting for a match for 1 day.",
+		},
+		{
+			recurser: Recurser{QueuedAt: now.AddDate(0, 0, -3), UnmatchedDays: 2},
+			want:     "You've been waiting for a match for 3 days. You went unmatched 2 days in a row, so you're near the front of the line!",
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := fmtWaitTime(test.recurser, now)
+		if got != test.want {
+			t.Errorf("%s: Expected %q, got %q", name, test.want, got)
+		}
+	}
+}
+
+func TestWebhookRejectsBadToken(t *testing.T) {
+	newTestStore(t)
+
+	w := httptest.NewRecorder()
+	body := `{"data": "subscribe", "token": "not-the-token", "trigger": "private_message", "message": {"sender_id": 5}}`
+	Webhook(w, httptest.NewRequest("POST", "/webhooks", strings.NewReader(body)))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 for a bad token, got %v", w.Code)
+	}
+	if _, ok, _ := store.GetRecurser(context.Background(), "5"); ok {
+		t.Errorf("Expected no one to be subscribed by a forged webhook")
+	}
+}
+
+func TestVerifyLink(t *testing.T) {
+	now := time.Now()
+	token := signLink("config", "1", now)
+	expires := strings.Split(token, ".")[0]
+
+	table := []struct {
+		page  string
+		id    string
+		token string
+		now   time.Time
+		want  error
+	}{
+		{"config", "1", token, now, nil},
+		{"config", "1", token, now.Add(linkTTL - time.Minute), nil},
+		{"config", "1", token, now.Add(linkTTL), errLinkExpired},
+		{"config", "2", token, now, errLinkInvalid},
+		{"history", "1", token, now, errLinkInvalid},
+		{"config", "1", "", now, errLinkInvalid},
+		{"config", "1", "9999999999." + strings.Split(token, ".")[1], now, errLinkInvalid},
+		{"config", "1", expires + ".forged", now, errLinkInvalid},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		got := verifyLink(test.page, test.id, test.token, test.now)
+		if got != test.want {
+			t.Errorf("%s: Expected %v, got %v", name, test.want, got)
+		}
+	}
+}
+
+func TestConfigLinks(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	useTemplates(t)
+
+	post := func(id string, token string, comments string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		form := validConfigForm()
+		form.Set("comments", comments)
+		r := httptest.NewRequest("POST", "/config/"+id+"?token="+token, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		Config(w, mux.SetURLVars(r, map[string]string{"id": id}))
+		return w
+	}
+
+	// Ada's link doesn't work for Grace, or once it's expired
+	if w := post("2", signLink("config", "1", time.Now()), "forged"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "copied wrong or changed") {
+		t.Errorf("Expected a tampered link to be rejected, got %v: %q", w.Code, w.Body.String())
+	}
+	if w := post("1", signLink("config", "1", time.Now().Add(-linkTTL)), "stale"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "This link has expired") {
+		t.Errorf("Expected an expired link to be rejected, got %v: %q", w.Code, w.Body.String())
+	}
+	for _, id := range []string{"1", "2"} {
+		if recurser, _, _ := s.GetRecurser(ctx, id); recurser.Config.Comments == "forged" || recurser.Config.Comments == "stale" {
+			t.Errorf("Expected a rejected link to leave recurser %v's config alone", id)
+		}
+	}
+
+	post("1", signLink("config", "1", time.Now()), "signed")
+	if ada, _, _ := s.GetRecurser(ctx, "1"); ada.Config.Comments != "signed" {
+		t.Errorf("Expected a signed link to update Ada's config, got %q", ada.Config.Comments)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/config/1", nil)
+	Config(w, mux.SetURLVars(r, map[string]string{"id": "1"}))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected a link without a token to be rejected, got %v", w.Code)
+	}
+
+	if got := config("1", Recurser{Name: "Ada"}, true); !strings.Contains(got, "/config/1?token=") {
+		t.Errorf("Expected the config command to hand out a signed link, got %q", got)
+	}
+}
+
+// useTemplates points the pages the bot serves at the repo's templates
+func useTemplates(t *testing.T) {
+	dir := templateDir
+	templateDir = "../../static/templates"
+	t.Cleanup(func() {
+		templateDir = dir
+	})
+}
+
+// validConfigForm is a config page filled in with every field
+func validConfigForm() url.Values {
+	return url.Values{
+		"experience":        {"medium"},
+		"questionList":      {"topInterview"},
+		"sequential":        {"sequential"},
+		"topicSelection":    {"manualTopic"},
+		"topics":            {"graph", "tree"},
+		"soloDays":          {"tue", "thu"},
+		"soloTime":          {"09:00"},
+		"timezone":          {"Europe/London"},
+		"soloDifficulty":    {"medium", "hard"},
+		"questionSelection": {"manualQuestion"},
+		"pairingDifficulty": {"hard"},
+		"environment":       {"replit"},
+		"comments":          {"I like trees"},
+	}
+}
+
+func TestParseConfigForm(t *testing.T) {
+	want := UserConfig{
+		Comments:          "I like trees",
+		Environment:       "replit",
+		Experience:        "medium",
+		ProblemSet:        "topInterview",
+		Sequential:        true,
+		Topics:            []string{"graph", "tree"},
+		SoloDays:          []string{"tue", "thu"},
+		SoloTime:          "09:00",
+		Timezone:          "Europe/London",
+		SoloDifficulty:    []string{"medium", "hard"},
+		PairingDifficulty: []string{"hard"},
+		ManualQuestion:    true,
+	}
+	if page := parseConfigForm(validConfigForm()); page.Errors != nil || !reflect.DeepEqual(page.Config, want) {
+		t.Errorf("Expected %v without errors, got %v with %v", want, page.Config, page.Errors)
+	}
+
+	table := []struct {
+		field  string
+		values []string
+		want   string
+	}{
+		{"experience", nil, "Please choose the level you're comfortable with."},
+		{"questionList", []string{"everything"}, "Please choose where your questions come from."},
+		{"topics", []string{"graph", "magic"}, "\"magic\" isn't a topic I know."},
+		{"topics", nil, "Please pick at least one topic, or choose all topics."},
+		{"soloDays", []string{"someday"}, "\"someday\" isn't a day I know."},
+		{"soloTime", []string{"09:30"}, "I only send questions on the hour, so \"09:30\" won't work. Try 09:00 instead."},
+		{"timezone", []string{"Mars/Olympus"}, "\"Mars/Olympus\" isn't a time zone I know. Try a name like America/New_York or Europe/London."},
+		{"soloDifficulty", nil, "Please pick at least one difficulty."},
+		{"pairingDifficulty", []string{"impossible"}, "\"impossible\" isn't a difficulty I know."},
+		{"environment", []string{"whiteboard"}, "Please choose an environment."},
+		{"comments", []string{strings.Repeat("a", maxCommentsLength+1)}, "Please keep your comments under 500 characters."},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		form := validConfigForm()
+		form[test.field] = test.values
+		page := parseConfigForm(form)
+		if len(page.Errors) != 1 || page.Errors[test.field] != test.want {
+			t.Errorf("%s: Expected %v to be %q, got %v", name, test.field, test.want, page.Errors)
+		}
+	}
+
+	// all topics clears any that were ticked, and blanks fall back to defaults
+	form := validConfigForm()
+	form.Set("topicSelection", "randomTopic")
+	form.Set("soloTime", "")
+	form.Set("timezone", "")
+	form.Set("comments", " ")
+	page := parseConfigForm(form)
+	if page.Errors != nil || len(page.Config.Topics) != 0 || page.Config.SoloTime != defaultSoloTime || page.Config.Timezone != defaultTimezone || page.Config.Comments != "N/A" {
+		t.Errorf("Expected defaults without errors, got %v with %v", page.Config, page.Errors)
+	}
+}
+
+func TestConfigPage(t *testing.T) {
+	s := newTestStore(t)
+	useTemplates(t)
+	ctx := context.Background()
+	token := signLink("config", "3", time.Now())
+
+	serve := func(method string, form url.Values) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/config/3?token="+token, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		Config(w, mux.SetURLVars(r, map[string]string{"id": "3"}))
+		return w
+	}
+	checked := func(body string, name string, value string) bool {
+		for _, input := range strings.Split(body, "<input")[1:] {
+			input = input[:strings.Index(input, "/>")]
+			if strings.Contains(input, `name="`+name+`"`) && strings.Contains(input, `value="`+value+`"`) {
+				return strings.Contains(input, `checked="checked"`)
+			}
+		}
+		return false
+	}
+
+	// the form starts out with Alan's settings
+	w := serve("GET", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the config page, got %v: %q", w.Code, w.Body.String())
+	}
+	for _, field := range [][2]string{
+		{"experience", "easy"}, {"questionList", "top100Liked"}, {"topicSelection", "manualTopic"}, {"topics", "array"},
+		{"soloDays", "mon"}, {"soloDays", "fri"}, {"soloDifficulty", "easy"}, {"questionSelection", "manualQuestion"}, {"environment", "googleDocs"},
+	} {
+		if !checked(w.Body.String(), field[0], field[1]) {
+			t.Errorf("Expected %v to be checked", field)
+		}
+	}
+	for _, field := range [][2]string{
+		{"experience", "medium"}, {"sequential", "sequential"}, {"topicSelection", "weakTopic"}, {"topics", "graph"},
+		{"soloDays", "tue"}, {"pairingDifficulty", "medium"}, {"questionSelection", "randomQuestion"}, {"environment", "leetcode"},
+	} {
+		if checked(w.Body.String(), field[0], field[1]) {
+			t.Errorf("Expected %v not to be checked", field)
+		}
+	}
+	if !strings.Contains(w.Body.String(), `value="11:00"`) {
+		t.Errorf("Expected Alan's solo time to be filled in")
+	}
+
+	// a bad field is pointed out and nothing is saved
+	form := validConfigForm()
+	form.Set("timezone", "Mars/Olympus")
+	w = serve("POST", form)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Mars/Olympus&#34; isn&#39;t a time zone I know") || !checked(w.Body.String(), "environment", "replit") {
+		t.Errorf("Expected the form back with an error on the time zone, got %v: %q", w.Code, w.Body.String())
+	}
+	if alan, _, _ := s.GetRecurser(ctx, "3"); alan.Config.Environment != "googleDocs" {
+		t.Errorf("Expected a bad form to leave Alan's config alone, got %v", alan.Config)
+	}
+
+	w = serve("POST", validConfigForm())
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Settings Saved!") || !strings.Contains(w.Body.String(), "Your preferred environment is replit.") {
+		t.Errorf("Expected the saved page, got %v: %q", w.Code, w.Body.String())
+	}
+	if alan, _, _ := s.GetRecurser(ctx, "3"); !reflect.DeepEqual(alan.Config, parseConfigForm(validConfigForm()).Config) {
+		t.Errorf("Expected Alan's config to be saved, got %v", alan.Config)
+	}
+
+	// someone who unsubscribed has nothing to configure
+	s.DeleteRecurser(ctx, "3")
+	if w = serve("GET", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 for someone who isn't subscribed, got %v", w.Code)
+	}
+}
+
+// addHistory gives Ada solo sessions on days 1, 3 and 5 of October 2026 and
+// pairing sessions with Grace and Alan, who then leaves
+func addHistory(t *testing.T, s Store) {
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 11, 0, 0, 0, time.UTC)
+	}
+
+	for _, session := range []SoloSession{
+		{Question: 1, TimeStamp: day(1), Outcome: outcomeSolved, Minutes: 20, Rating: 4},
+		{Question: 297, TimeStamp: day(3), Outcome: outcomeGaveUp},
+		{Question: 4, TimeStamp: day(5), Review: true},
+	} {
+		if err := s.AppendSoloSession(ctx, "1", session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, session := range map[string]PairingSession{
+		"1": {Interviewer: "2", Interviewee: "1", Question: 297, TimeStamp: day(2)},
+		"2": {Interviewer: "1", Interviewee: "2", Question: 104, TimeStamp: day(2)},
+	} {
+		if err := s.AppendPairingSession(ctx, id, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.AppendPairingSession(ctx, "1", PairingSession{Interviewer: "3", Interviewee: "1", TimeStamp: day(4)}); err != nil {
+		t.Fatal(err)
+	}
+	s.DeleteRecurser(ctx, "3")
+	s.DeleteSessionHistory(ctx, "3")
+}
+
+func TestLoadHistory(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	addHistory(t, s)
+	ada, _, _ := s.GetRecurser(ctx, "1")
+
+	table := []struct {
+		filters     historyFilters
+		wantSolo    []int
+		wantPairing []string
+	}{
+		{
+			filters:     historyFilters{},
+			wantSolo:    []int{4, 297, 1},
+			wantPairing: []string{"Interviewee with Someone who has since left AlgoBot on 0", "Interviewee with Grace Hopper on 297", "Interviewer with Grace Hopper on 104"},
+		},
+		{
+			filters:     historyFilters{From: "2026-10-02", To: "2026-10-03"},
+			wantSolo:    []int{297},
+			wantPairing: []string{"Interviewee with Grace Hopper on 297", "Interviewer with Grace Hopper on 104"},
+		},
+		{
+			filters:     historyFilters{Topic: "tree"},
+			wantSolo:    []int{297},
+			wantPairing: []string{"Interviewee with Grace Hopper on 297", "Interviewer with Grace Hopper on 104"},
+		},
+		{
+			filters:     historyFilters{Topic: "array", Difficulty: "hard"},
+			wantSolo:    []int{4},
+			wantPairing: nil,
+		},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		page, err := loadHistory(ada, test.filters, s, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var solo []int
+		for _, entry := range page.Solo {
+			solo = append(solo, entry.Question.Id)
+		}
+		var pairing []string
+		for _, entry := range page.Pairing {
+			question := 0
+			if entry.Question != nil {
+				question = entry.Question.Id
+			}
+			pairing = append(pairing, fmt.Sprintf("%s with %s on %d", entry.Role, entry.Partner, question))
+		}
+		if !reflect.DeepEqual(solo, test.wantSolo) || !reflect.DeepEqual(pairing, test.wantPairing) {
+			t.Errorf("%s: Expected %v and %v, got %v and %v", name, test.wantSolo, test.wantPairing, solo, pairing)
+		}
+		if page.SoloTotal != 3 || page.PairingTotal != 3 {
+			t.Errorf("%s: Expected 3 sessions of each, got %v and %v", name, page.SoloTotal, page.PairingTotal)
+		}
+	}
+}
+
+func TestFmtOutcome(t *testing.T) {
+	table := []struct {
+		session SoloSession
+		want    string
+	}{
+		{SoloSession{}, "No word yet"},
+		{SoloSession{Rating: 2}, "No word yet, rated 2/5"},
+		{SoloSession{Outcome: outcomeSolved}, "Solved"},
+		{SoloSession{Outcome: outcomeSolved, Minutes: 1, Rating: 5}, "Solved in 1 minute, rated 5/5"},
+		{SoloSession{Outcome: outcomeGaveUp, Minutes: 45}, "Gave up after 45 minutes"},
+	}
+
+	for i, test := range table {
+		name := fmt.Sprintf("Test %v", i)
+		if got := fmtOutcome(test.session); got != test.want {
+			t.Errorf("%s: Expected %q, got %q", name, test.want, got)
+		}
+	}
+}
+
+func TestHistoryPage(t *testing.T) {
+	s := newTestStore(t)
+	useTemplates(t)
+	addHistory(t, s)
+
+	serve := func(token string, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/history/1?token="+token+query, nil)
+		History(w, mux.SetURLVars(r, map[string]string{"id": "1"}))
+		return w
+	}
+	token := signLink("history", "1", time.Now())
+
+	// a config link doesn't open the history page
+	if w := serve(signLink("config", "1", time.Now()), ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a link for another page to be rejected, got %v", w.Code)
+	}
+
+	w := serve(token, "")
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the history page, got %v: %q", w.Code, body)
+	}
+	for _, want := range []string{"Ada Lovelace", "Two Sum", "Solved in 20 minutes, rated 4/5", "No word yet (review)", "hashTable", "Grace Hopper", "Chosen by the interviewer", "Showing 3 of 3"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the page to include %q", want)
+		}
+	}
+
+	w = serve(token, "&topic=tree&difficulty=easy&from=someday")
+	body = w.Body.String()
+	if !strings.Contains(body, "Maximum Depth of Binary Tree") || strings.Contains(body, "Two Sum") || strings.Contains(body, "Serialize") {
+		t.Errorf("Expected only easy tree questions, got %q", body)
+	}
+	if !strings.Contains(body, "someday&#34; isn&#39;t a date like 2026-11-02.") || !strings.Contains(body, `<option value="tree" selected="selected">`) {
+		t.Errorf("Expected the filters to be kept and the bad date pointed out, got %q", body)
+	}
+
+	if got := history("1", Recurser{}, true); !strings.Contains(got, "/history/1?token=") {
+		t.Errorf("Expected the history command to hand out a signed link, got %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ZulipSender is what the cron jobs need from Zulip; tests swap in a fake
+type ZulipSender interface {
+	SendPrivate(to []string, content string) (MessageResponse, error)
+	SendStream(stream string, topic string, content string) (MessageResponse, error)
+}
+
+// ZulipClient talks to the Zulip REST API as the bot user
+// https://zulip.com/api/send-message
+type ZulipClient struct {
+	serverURL  string
+	email      string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// MessageResponse is what Zulip sends back for a successfully sent message
+type MessageResponse struct {
+	Id int `json:"id"`
+}
+
+// ZulipError is a failed API call, e.g. an unknown recipient or hitting the rate limit.
+// Code holds Zulip's error code such as "BAD_REQUEST" or "RATE_LIMIT_HIT".
+type ZulipError struct {
+	StatusCode int
+	Code       string
+	Msg        string
+}
+
+func (e *ZulipError) Error() string {
+	return fmt.Sprintf("zulip: %s (%d %s)", e.Msg, e.StatusCode, e.Code)
+}
+
+func NewZulipClient(serverURL string, email string, apiKey string) *ZulipClient {
+	return &ZulipClient{
+		serverURL:  strings.TrimSuffix(serverURL, "/"),
+		email:      email,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// newZulipClient sets up a client for the bot using the API key kept in the store
+func newZulipClient(store Store, ctx context.Context) (*ZulipClient, error) {
+	apiKey, err := store.APIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewZulipClient(zulipServerURL, botEmailAddress, apiKey), nil
+}
+
+// SendPrivate sends a private message; more than one recipient makes it a group PM
+func (c *ZulipClient) SendPrivate(to []string, content string) (MessageResponse, error) {
+	recipients, err := json.Marshal(to)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	form := url.Values{}
+	form.Add("type", "private")
+	form.Add("to", string(recipients))
+	form.Add("content", content)
+	return c.sendMessage(form)
+}
+
+// SendStream posts a message to the given topic of a stream
+func (c *ZulipClient) SendStream(stream string, topic string, content string) (MessageResponse, error) {
+	form := url.Values{}
+	form.Add("type", "stream")
+	form.Add("to", stream)
+	form.Add("topic", topic)
+	form.Add("content", content)
+	return c.sendMessage(form)
+}
+
+func (c *ZulipClient) sendMessage(form url.Values) (MessageResponse, error) {
+	var response MessageResponse
+
+	req, err := http.NewRequest("POST", c.serverURL+"/api/v1/messages", strings.NewReader(form.Encode()))
+	if err != nil {
+		return response, err
+	}
+	req.SetBasicAuth(c.email, c.apiKey)
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	// every Zulip response carries a result; errors add a code and a human readable msg
+	var body struct {
+		Result string `json:"result"`
+		Msg    string `json:"msg"`
+		Code   string `json:"code"`
+		Id     int    `json:"id"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return response, &ZulipError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("unreadable response: %v", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK || body.Result != "success" {
+		return response, &ZulipError{StatusCode: resp.StatusCode, Code: body.Code, Msg: body.Msg}
+	}
+
+	response.Id = body.Id
+	return response, nil
+}
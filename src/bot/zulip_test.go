@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestZulipClient(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, key, ok := r.BasicAuth()
+		if !ok || email != botEmailAddress || key != "test-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"result": "error", "msg": "Invalid API key", "code": "UNAUTHORIZED"}`))
+			return
+		}
+		if r.URL.Path != "/api/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+
+		r.ParseForm()
+		got = map[string]string{}
+		for k := range r.PostForm {
+			got[k] = r.PostForm.Get(k)
+		}
+		if got["to"] == `["nobody@example.com"]` {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"result": "error", "msg": "Invalid email 'nobody@example.com'", "code": "BAD_REQUEST"}`))
+			return
+		}
+		w.Write([]byte(`{"result": "success", "msg": "", "id": 42}`))
+	}))
+	defer server.Close()
+
+	zulip := NewZulipClient(server.URL, botEmailAddress, "test-api-key")
+
+	resp, err := zulip.SendPrivate([]string{"ada@example.com", "grace@example.com"}, "Hi you two!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id != 42 {
+		t.Errorf("Expected message id 42, got %v", resp.Id)
+	}
+	if got["type"] != "private" || got["to"] != `["ada@example.com","grace@example.com"]` || got["content"] != "Hi you two!" {
+		t.Errorf("Unexpected private message request %v", got)
+	}
+
+	_, err = zulip.SendStream("Daily LeetCode", "AlgoBot Daily Question", "Two Sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["type"] != "stream" || got["to"] != "Daily LeetCode" || got["topic"] != "AlgoBot Daily Question" {
+		t.Errorf("Unexpected stream message request %v", got)
+	}
+
+	_, err = zulip.SendPrivate([]string{"nobody@example.com"}, "Hello?")
+	zulipErr, ok := err.(*ZulipError)
+	if !ok || zulipErr.Code != "BAD_REQUEST" || zulipErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a BAD_REQUEST error, got %v", err)
+	}
+
+	_, err = NewZulipClient(server.URL, botEmailAddress, "wrong-key").SendStream("Daily LeetCode", "AlgoBot Daily Question", "Two Sum")
+	zulipErr, ok = err.(*ZulipError)
+	if !ok || zulipErr.Code != "UNAUTHORIZED" {
+		t.Errorf("Expected an UNAUTHORIZED error, got %v", err)
+	}
+}